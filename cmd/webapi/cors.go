@@ -12,8 +12,13 @@ func applyCORSHandler(h http.Handler) http.Handler {
 	return handlers.CORS(
 		handlers.AllowedHeaders([]string{
 			"x-example-header", "Content-Type", "Authorization", "Access-Control-Allow-Origin", "Access-Control-Allow-Headers", "X-Requested-With",
+			"Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata",
+		}),
+		handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS", "DELETE", "PUT", "PATCH", "HEAD"}),
+		// Headers of the resumable uploads protocol, read by the web UI
+		handlers.ExposedHeaders([]string{
+			"Location", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Expires", "Photo-Id", "Duplicate-Of",
 		}),
-		handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS", "DELETE", "PUT"}),
 		// Do not modify the CORS origin and max age, they are used in the evaluation.
 		handlers.AllowedOrigins([]string{"*"}),
 		handlers.MaxAge(1),
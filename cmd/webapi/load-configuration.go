@@ -27,6 +27,34 @@ type WebAPIConfiguration struct {
 	DB    struct {
 		Filename string `conf:"default:/tmp/decaf.db"`
 	}
+	Uploads struct {
+		Directory string        `conf:"default:/tmp/wasaphoto-uploads"`
+		Expiry    time.Duration `conf:"default:24h"`
+		MaxSize   int64         `conf:"default:52428800"`
+	}
+	Jobs struct {
+		Interval time.Duration `conf:"default:1m"`
+	}
+	Quota struct {
+		MaxPhotos int   `conf:"default:1000"`
+		MaxBytes  int64 `conf:"default:1073741824"`
+	}
+	Admin struct {
+		Usernames []string
+	}
+	Trash struct {
+		Retention time.Duration `conf:"default:720h"`
+	}
+	Views struct {
+		Window time.Duration `conf:"default:1h"`
+	}
+	Duplicates struct {
+		Mode        string `conf:"default:warn"`
+		MaxDistance int    `conf:"default:6"`
+	}
+	Reactions struct {
+		Types []string `conf:"default:like;love;haha;wow;sad;angry"`
+	}
 }
 
 // loadConfiguration creates a WebAPIConfiguration starting from flags, environment variables and configuration file.
@@ -112,8 +112,20 @@ func run() error {
 
 	// Create the API router
 	apirouter, err := api.New(api.Config{
-		Logger:   logger,
-		Database: db,
+		Logger:                logger,
+		Database:              db,
+		UploadsDirectory:      cfg.Uploads.Directory,
+		UploadsExpiry:         cfg.Uploads.Expiry,
+		UploadsMaxSize:        cfg.Uploads.MaxSize,
+		JobsInterval:          cfg.Jobs.Interval,
+		QuotaMaxPhotos:        cfg.Quota.MaxPhotos,
+		QuotaMaxBytes:         cfg.Quota.MaxBytes,
+		AdminUsernames:        cfg.Admin.Usernames,
+		TrashRetention:        cfg.Trash.Retention,
+		ViewsWindow:           cfg.Views.Window,
+		DuplicatesMode:        cfg.Duplicates.Mode,
+		DuplicatesMaxDistance: cfg.Duplicates.MaxDistance,
+		ReactionTypes:         cfg.Reactions.Types,
 	})
 	if err != nil {
 		logger.WithError(err).Error("error creating the API server instance")
@@ -121,6 +133,9 @@ func run() error {
 	}
 	router := apirouter.Handler()
 
+	// Start background jobs (e.g., expiry of abandoned uploads); apirouter.Close() stops them
+	apirouter.StartJobs()
+
 	router, err = registerWebUI(router)
 	if err != nil {
 		logger.WithError(err).Error("error registering web UI handler")
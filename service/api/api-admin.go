@@ -0,0 +1,236 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// authenticateAdmin autentica l'utente della richiesta e verifica che sia un amministratore. In caso di errore scrive la
+// risposta e restituisce false.
+func (rt *_router) authenticateAdmin(w http.ResponseWriter, r *http.Request, ctx reqcontext.RequestContext) (database.User, bool) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return database.User{}, false
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, false
+	}
+
+	if !rt.isAdmin(user) {
+		http.Error(w, "Forbidden: administrators only", http.StatusForbidden)
+		return user, false
+	}
+
+	return user, true
+}
+
+// loadQuotaUser legge l'utente userID di cui si gestisce la quota. In caso di errore scrive la risposta e restituisce
+// false.
+func loadQuotaUser(w http.ResponseWriter, ctx reqcontext.RequestContext, userID string) (database.User, bool) {
+	user, err := ctx.Database.GetUserById(userID)
+	if err != nil || user.ID == 0 {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+	return user, true
+}
+
+// writeQuota risponde con la quota attuale dell'utente userID
+func (rt *_router) writeQuota(w http.ResponseWriter, ctx reqcontext.RequestContext, userID string) {
+	quota, err := rt.getQuota(ctx.Database, userID)
+	if err != nil {
+		log.Printf("Error retrieving quota: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(quota)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getUserQuota restituisce a un amministratore la quota di un utente e lo spazio che ha usato
+func (rt *_router) getUserQuota(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := rt.authenticateAdmin(w, r, ctx); !ok {
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if _, ok := loadQuotaUser(w, ctx, userID); !ok {
+		return
+	}
+
+	rt.writeQuota(w, ctx, userID)
+}
+
+// setUserQuota permette a un amministratore di personalizzare i limiti di un utente. Un limite omesso (o null) resta
+// quello predefinito, un limite pari a 0 toglie il limite.
+func (rt *_router) setUserQuota(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := rt.authenticateAdmin(w, r, ctx); !ok {
+		return
+	}
+
+	userID := ps.ByName("userId")
+	user, ok := loadQuotaUser(w, ctx, userID)
+	if !ok {
+		return
+	}
+
+	var override database.QuotaOverride
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if (override.MaxPhotos != nil && *override.MaxPhotos < 0) || (override.MaxBytes != nil && *override.MaxBytes < 0) {
+		http.Error(w, "Bad Request: limits cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	override.UserID = user.ID
+	err := ctx.Database.SetQuotaOverride(override)
+	if err != nil {
+		log.Printf("Error saving quota: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	rt.writeQuota(w, ctx, userID)
+}
+
+// deleteUserQuota permette a un amministratore di riportare un utente ai limiti predefiniti
+func (rt *_router) deleteUserQuota(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := rt.authenticateAdmin(w, r, ctx); !ok {
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if _, ok := loadQuotaUser(w, ctx, userID); !ok {
+		return
+	}
+
+	err := ctx.Database.DeleteQuotaOverride(userID)
+	if err != nil {
+		log.Printf("Error deleting quota: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadBlockedHashParam legge l'hash percettivo hash del percorso, in esadecimale minuscolo. In caso di errore scrive
+// la risposta e restituisce false.
+func loadBlockedHashParam(w http.ResponseWriter, ps httprouter.Params) (string, bool) {
+	hash := strings.ToLower(ps.ByName("hash"))
+	if _, ok := parsePerceptualHash(hash); !ok {
+		http.Error(w, "Bad Request: the hash must be 16 hexadecimal digits", http.StatusBadRequest)
+		return hash, false
+	}
+	return hash, true
+}
+
+// getBlockedHashes restituisce a un amministratore gli hash percettivi delle immagini che non possono essere
+// pubblicate
+func (rt *_router) getBlockedHashes(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := rt.authenticateAdmin(w, r, ctx); !ok {
+		return
+	}
+
+	blocked, err := ctx.Database.GetBlockedHashes()
+	if err != nil {
+		log.Printf("Error retrieving blocked hashes: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if blocked == nil {
+		blocked = []database.BlockedHash{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(blocked)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// blockHash permette a un amministratore di bloccare un hash percettivo: uploadPhoto e gli upload ripristinabili
+// rifiutano le immagini quasi identiche. Il corpo, facoltativo, indica il motivo del blocco.
+func (rt *_router) blockHash(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := rt.authenticateAdmin(w, r, ctx); !ok {
+		return
+	}
+
+	hash, ok := loadBlockedHashParam(w, ps)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	blocked := database.BlockedHash{
+		Hash:      hash,
+		Reason:    strings.TrimSpace(body.Reason),
+		Timestamp: globaltime.Now().Format(timestampFormat),
+	}
+	err := ctx.Database.SetBlockedHash(blocked)
+	if err != nil {
+		log.Printf("Error blocking hash %s: %v", hash, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(blocked)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// unblockHash permette a un amministratore di sbloccare un hash percettivo
+func (rt *_router) unblockHash(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := rt.authenticateAdmin(w, r, ctx); !ok {
+		return
+	}
+
+	hash, ok := loadBlockedHashParam(w, ps)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.DeleteBlockedHash(hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Hash not blocked", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error unblocking hash %s: %v", hash, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,435 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// albumRequest è il corpo JSON delle richieste di creazione e modifica di un album
+type albumRequest struct {
+	Title        string `json:"title"`
+	CoverPhotoID *int   `json:"cover_photo_id"`
+}
+
+// loadUserAlbum legge l'album albumID, con le foto visibili a viewer, controllando che appartenga a userID. In caso di
+// errore scrive la risposta e restituisce false.
+func loadUserAlbum(w http.ResponseWriter, ctx reqcontext.RequestContext, viewer database.User, userID string, albumID string) (database.Album, bool) {
+	album, err := ctx.Database.GetAlbumByID(albumID, strconv.Itoa(viewer.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return album, false
+	} else if err != nil {
+		log.Printf("Error retrieving album %s: %v", albumID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return album, false
+	}
+
+	if strconv.Itoa(album.UserID) != userID {
+		http.Error(w, "Album not found", http.StatusNotFound)
+		return album, false
+	}
+
+	return album, true
+}
+
+// checkCoverPhoto verifica che la copertina scelta sia una foto di user. In caso di errore scrive la risposta e
+// restituisce false.
+func checkCoverPhoto(w http.ResponseWriter, ctx reqcontext.RequestContext, user database.User, coverPhotoID *int) bool {
+	if coverPhotoID == nil {
+		return true
+	}
+
+	photo, err := ctx.Database.GetPhotoByID(strconv.Itoa(*coverPhotoID))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && photo.UserID != user.ID) {
+		http.Error(w, "Bad Request: cover photo not found", http.StatusBadRequest)
+		return false
+	} else if err != nil {
+		log.Printf("Error retrieving cover photo: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+// createAlbum crea un nuovo album vuoto per l'utente autenticato
+func (rt *_router) createAlbum(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Solo il proprietario può creare album nel proprio profilo
+	userID := ps.ByName("userId")
+	if strconv.Itoa(user.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var reqBody albumRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reqBody.Title = strings.TrimSpace(reqBody.Title)
+	if reqBody.Title == "" {
+		http.Error(w, "Bad Request: title is required", http.StatusBadRequest)
+		return
+	}
+
+	if !checkCoverPhoto(w, ctx, user, reqBody.CoverPhotoID) {
+		return
+	}
+
+	timestamp := globaltime.Now().Format(timestampFormat)
+	albumID, err := ctx.Database.SetAlbum(userID, reqBody.Title, reqBody.CoverPhotoID, timestamp)
+	if err != nil {
+		log.Printf("Error creating album: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	album := database.Album{
+		ID:           int(albumID),
+		UserID:       user.ID,
+		Title:        reqBody.Title,
+		CoverPhotoID: reqBody.CoverPhotoID,
+		Timestamp:    timestamp,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(album)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// getUserAlbums restituisce gli album di un utente, senza le foto
+func (rt *_router) getUserAlbums(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	isBanned, err := ctx.Database.IsBanned(strconv.Itoa(user.ID), userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if isBanned {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	albums, err := ctx.Database.GetAlbumsByUserID(userID, strconv.Itoa(user.ID))
+	if err != nil {
+		log.Printf("Error retrieving albums: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(albums)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// getAlbum restituisce un album con le sue foto in ordine
+func (rt *_router) getAlbum(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	isBanned, err := ctx.Database.IsBanned(strconv.Itoa(user.ID), userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if isBanned {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	album, ok := loadUserAlbum(w, ctx, user, userID, ps.ByName("albumId"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(album)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// updateAlbum modifica titolo e copertina di un album
+func (rt *_router) updateAlbum(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if strconv.Itoa(user.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	albumID := ps.ByName("albumId")
+	if _, ok := loadUserAlbum(w, ctx, user, userID, albumID); !ok {
+		return
+	}
+
+	var reqBody albumRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reqBody.Title = strings.TrimSpace(reqBody.Title)
+	if reqBody.Title == "" {
+		http.Error(w, "Bad Request: title is required", http.StatusBadRequest)
+		return
+	}
+
+	if !checkCoverPhoto(w, ctx, user, reqBody.CoverPhotoID) {
+		return
+	}
+
+	err = ctx.Database.UpdateAlbum(albumID, reqBody.Title, reqBody.CoverPhotoID)
+	if err != nil {
+		log.Printf("Error updating album: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteAlbum elimina un album senza eliminare le sue foto
+func (rt *_router) deleteAlbum(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if strconv.Itoa(user.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	albumID := ps.ByName("albumId")
+	if _, ok := loadUserAlbum(w, ctx, user, userID, albumID); !ok {
+		return
+	}
+
+	err = ctx.Database.DeleteAlbum(albumID)
+	if err != nil {
+		log.Printf("Error deleting album: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// addPhotoToAlbum aggiunge una foto dell'utente in fondo a un suo album
+func (rt *_router) addPhotoToAlbum(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if strconv.Itoa(user.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	albumID := ps.ByName("albumId")
+	if _, ok := loadUserAlbum(w, ctx, user, userID, albumID); !ok {
+		return
+	}
+
+	// Negli album si possono inserire solo le proprie foto
+	photoID := ps.ByName("photosId")
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && photo.UserID != user.ID) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving photo: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = ctx.Database.AddPhotoToAlbum(albumID, photoID)
+	if err != nil {
+		log.Printf("Error adding photo to album: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// removePhotoFromAlbum toglie una foto da un album senza eliminarla
+func (rt *_router) removePhotoFromAlbum(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if strconv.Itoa(user.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	albumID := ps.ByName("albumId")
+	if _, ok := loadUserAlbum(w, ctx, user, userID, albumID); !ok {
+		return
+	}
+
+	err = ctx.Database.RemovePhotoFromAlbum(albumID, ps.ByName("photosId"))
+	if err != nil {
+		log.Printf("Error removing photo from album: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// reorderAlbumPhotos cambia l'ordine delle foto di un album
+func (rt *_router) reorderAlbumPhotos(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if strconv.Itoa(user.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	albumID := ps.ByName("albumId")
+	if _, ok := loadUserAlbum(w, ctx, user, userID, albumID); !ok {
+		return
+	}
+
+	var reqBody struct {
+		PhotoIDs []int `json:"photo_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.Database.ReorderAlbumPhotos(albumID, reqBody.PhotoIDs)
+	if errors.Is(err, database.ErrAlbumOrderMismatch) {
+		http.Error(w, "Bad Request: photo_ids must list every photo of the album once", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("Error reordering album: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// archivePhoto archivia una foto dell'utente autenticato: non compare più agli altri utenti, né nel profilo né negli
+// stream, ma mantiene likes e commenti
+func (rt *_router) archivePhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photo, ok := loadOwnPhoto(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.ArchivePhoto(strconv.Itoa(photo.ID), globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error archiving photo %d: %v", photo.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unarchivePhoto toglie dall'archivio una foto dell'utente autenticato
+func (rt *_router) unarchivePhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photo, ok := loadOwnPhoto(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.UnarchivePhoto(strconv.Itoa(photo.ID))
+	if err != nil {
+		log.Printf("Error unarchiving photo %d: %v", photo.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getArchive restituisce le foto archiviate dell'utente autenticato, dall'ultima archiviata
+func (rt *_router) getArchive(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	photos, err := ctx.Database.GetArchivedPhotosByUserID(ps.ByName("userId"))
+	if err != nil {
+		log.Printf("Error retrieving archive: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if photos == nil {
+		photos = []database.Photo{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(photos)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// bookmarksPage è una pagina delle foto salvate
+type bookmarksPage struct {
+	Bookmarks  []database.Bookmark `json:"bookmarks"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// saveBookmark salva una foto tra le foto salvate dell'utente autenticato. La foto deve essere visibile all'utente.
+func (rt *_router) saveBookmark(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if !checkPhotoAccess(w, ctx, user, strconv.Itoa(photo.UserID), photoID) {
+		return
+	}
+
+	err = ctx.Database.SetBookmark(strconv.Itoa(user.ID), photoID, globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error saving bookmark: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteBookmark toglie una foto dalle foto salvate dell'utente autenticato
+func (rt *_router) deleteBookmark(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.DeleteBookmark(strconv.Itoa(user.ID), ps.ByName("photosId"))
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "Bookmark not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting bookmark: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getBookmarks restituisce una pagina delle foto salvate dall'utente autenticato, dall'ultima salvata. Le foto che
+// l'utente non può più vedere, ad esempio perché il proprietario lo ha bannato, non vengono restituite.
+func (rt *_router) getBookmarks(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Un elemento in più indica se esiste la pagina successiva
+	bookmarks, err := ctx.Database.GetBookmarksByUserID(strconv.Itoa(user.ID), cursor, limit+1)
+	if err != nil {
+		log.Printf("Error retrieving bookmarks: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	page := bookmarksPage{Bookmarks: []database.Bookmark{}}
+	if len(bookmarks) > limit {
+		bookmarks = bookmarks[:limit]
+		page.NextCursor = strconv.Itoa(bookmarks[limit-1].ID)
+	}
+	page.Bookmarks = append(page.Bookmarks, bookmarks...)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(page)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// commentHistory è un commento con le versioni precedenti del suo testo, dalla meno recente
+type commentHistory struct {
+	Comment  database.Comment          `json:"comment"`
+	Versions []database.CommentVersion `json:"versions"`
+}
+
+// editComment permette all'autore di un commento di modificarne il testo, inviato nel campo "comment" come alla
+// creazione. Il testo precedente resta nella cronologia del commento.
+func (rt *_router) editComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), ps.ByName("photosId")) {
+		return
+	}
+
+	comment, ok := loadThreadComment(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+	if comment.DeletedAt != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if comment.UserId != user.ID {
+		http.Error(w, "Forbidden: only the author can edit a comment", http.StatusForbidden)
+		return
+	}
+
+	text := r.FormValue("comment")
+	if err := validateCommentText(text); err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Le menzioni del nuovo testo sostituiscono quelle precedenti
+	mentions, err := resolveMentions(ctx.Database, user, text)
+	if errors.Is(err, errMentionBanned) {
+		http.Error(w, "Forbidden: a mentioned user has banned you", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Printf("Error resolving comment mentions: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	commentID := strconv.Itoa(comment.ID)
+	err = ctx.Database.EditComment(commentID, text, mentions, globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error editing comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	comment, err = ctx.Database.GetCommentByID(commentID, strconv.Itoa(user.ID))
+	if err != nil {
+		log.Printf("Error retrieving comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(comment)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// getCommentHistory restituisce al proprietario della foto un commento insieme alle versioni precedenti del suo
+// testo, per moderare i commenti modificati
+func (rt *_router) getCommentHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	if _, ok := loadOwnPhoto(w, ps, ctx, user); !ok {
+		return
+	}
+
+	comment, ok := loadThreadComment(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+
+	versions, err := ctx.Database.GetCommentHistory(strconv.Itoa(comment.ID))
+	if err != nil {
+		log.Printf("Error retrieving history of comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	history := commentHistory{Comment: comment, Versions: []database.CommentVersion{}}
+	history.Versions = append(history.Versions, versions...)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(history)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// loadLikedComment autentica l'utente e restituisce il commento commentsId del percorso, che deve essere visibile
+// all'utente e non eliminato. In caso di errore scrive la risposta e restituisce false.
+func loadLikedComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) (database.User, database.Comment, bool) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return database.User{}, database.Comment{}, false
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, database.Comment{}, false
+	}
+
+	// Si può mettere like solo ai commenti delle foto che si possono vedere
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), ps.ByName("photosId")) {
+		return user, database.Comment{}, false
+	}
+
+	comment, ok := loadThreadComment(w, ps, ctx, user)
+	if !ok {
+		return user, comment, false
+	}
+	if comment.DeletedAt != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return user, comment, false
+	}
+
+	return user, comment, true
+}
+
+// likeComment aggiunge il like dell'utente autenticato a un commento; come per le foto, il like è la reazione
+// predefinita e sostituisce l'eventuale reazione di altro tipo, mentre mettere di nuovo like non ha effetto
+func (rt *_router) likeComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, comment, ok := loadLikedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.SetCommentReaction(strconv.Itoa(user.ID), strconv.Itoa(comment.ID), database.DefaultReaction,
+		globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error liking comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// unlikeComment toglie il like dell'utente autenticato a un commento
+func (rt *_router) unlikeComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, comment, ok := loadLikedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.DeleteCommentReaction(strconv.Itoa(user.ID), strconv.Itoa(comment.ID), database.DefaultReaction)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Like not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error unliking comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// loadReactedComment è come loadLikedComment, ma verifica anche che il tipo di reazione del percorso sia tra quelli
+// configurati. In caso di errore scrive la risposta e restituisce false.
+func (rt *_router) loadReactedComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) (database.User, database.Comment, string, bool) {
+	user, comment, ok := loadLikedComment(w, r, ps, ctx)
+	if !ok {
+		return user, comment, "", false
+	}
+
+	reaction, ok := rt.loadReactionParam(w, ps)
+	return user, comment, reaction, ok
+}
+
+// setCommentReaction imposta la reazione dell'utente autenticato a un commento, sostituendo quella che aveva già messo
+func (rt *_router) setCommentReaction(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, comment, reaction, ok := rt.loadReactedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.SetCommentReaction(strconv.Itoa(user.ID), strconv.Itoa(comment.ID), reaction,
+		globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error setting reaction to comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteCommentReaction toglie la reazione dell'utente autenticato a un commento, se è del tipo indicato
+func (rt *_router) deleteCommentReaction(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, comment, reaction, ok := rt.loadReactedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.DeleteCommentReaction(strconv.Itoa(user.ID), strconv.Itoa(comment.ID), reaction)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Reaction not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting reaction to comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// writeFollowRequests risponde con la lista di richieste di follow
+func writeFollowRequests(w http.ResponseWriter, requests []database.FollowRequest) {
+	if requests == nil {
+		requests = []database.FollowRequest{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(requests)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// getFollowRequests restituisce le richieste di follow ricevute dall'utente autenticato, dalla più recente
+func (rt *_router) getFollowRequests(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	requests, err := ctx.Database.GetFollowRequestsByTarget(ps.ByName("userId"))
+	if err != nil {
+		log.Printf("Error retrieving follow requests: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeFollowRequests(w, requests)
+}
+
+// getSentFollowRequests restituisce le richieste di follow inviate dall'utente autenticato e non ancora approvate o
+// rifiutate; una richiesta si annulla con unfollowUser
+func (rt *_router) getSentFollowRequests(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	requests, err := ctx.Database.GetFollowRequestsByRequester(ps.ByName("userId"))
+	if err != nil {
+		log.Printf("Error retrieving follow requests: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeFollowRequests(w, requests)
+}
+
+// approveFollowRequest approva la richiesta di follow di requesterId: da quel momento requesterId segue l'utente
+func (rt *_router) approveFollowRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	err := ctx.Database.ApproveFollowRequest(ps.ByName("requesterId"), ps.ByName("userId"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Follow request not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error approving follow request: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// rejectFollowRequest rifiuta la richiesta di follow di requesterId
+func (rt *_router) rejectFollowRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	err := ctx.Database.DeleteFollowRequest(ps.ByName("requesterId"), ps.ByName("userId"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Follow request not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error rejecting follow request: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
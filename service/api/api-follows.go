@@ -0,0 +1,125 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// followsPage è una pagina dei follower o dei seguiti di un utente
+type followsPage struct {
+	Users      []database.Follow `json:"users"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// followListFunc è GetFollowers o GetFollows del database
+type followListFunc func(userID string, viewerID string, prefix string, after int, limit int) ([]database.Follow, error)
+
+// getFollowers restituisce una pagina dei follower di un utente, dal follow più recente
+func (rt *_router) getFollowers(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	writeFollowsPage(w, r, ps, ctx, ctx.Database.GetFollowers)
+}
+
+// getFollowing restituisce una pagina degli utenti seguiti da un utente, dal follow più recente
+func (rt *_router) getFollowing(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	writeFollowsPage(w, r, ps, ctx, ctx.Database.GetFollows)
+}
+
+// writeFollowsPage risponde con la pagina richiesta della lista list dell'utente userId del percorso, filtrata per
+// prefisso dello username con il parametro "username". Come per il profilo, la lista non è visibile a chi è stato
+// bannato dall'utente.
+func writeFollowsPage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext, list followListFunc) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if _, err := ctx.Database.IsPrivate(userID); errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving user %s: %v", userID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	isBanned, err := ctx.Database.IsBanned(strconv.Itoa(user.ID), userID)
+	if err != nil {
+		log.Printf("Error checking ban: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if isBanned {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Un elemento in più indica se esiste la pagina successiva
+	follows, err := list(userID, strconv.Itoa(user.ID), r.URL.Query().Get("username"), cursor, limit+1)
+	if err != nil {
+		log.Printf("Error retrieving follows of user %s: %v", userID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	page := followsPage{Users: []database.Follow{}}
+	if len(follows) > limit {
+		follows = follows[:limit]
+		page.NextCursor = strconv.Itoa(follows[limit-1].ID)
+	}
+	page.Users = append(page.Users, follows...)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(page)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// getRelationship restituisce in una sola risposta il rapporto dell'utente autenticato con l'utente otherId, in entrambi
+// i versi, al posto di getIsFollowed e getIsBanned
+func (rt *_router) getRelationship(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	otherID := ps.ByName("otherId")
+	relationship, err := ctx.Database.GetRelationship(strconv.Itoa(user.ID), otherID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving relationship with user %s: %v", otherID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(relationship)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -14,31 +14,118 @@ func (rt *_router) Handler() http.Handler {
 	rt.router.GET("/users", rt.wrap(rt.searchUser))
 	rt.router.GET("/users/:userId/profile", rt.wrap(rt.getUserProfile))
 	rt.router.PUT("/users/:userId/profile/edit", rt.wrap(rt.setMyUserName))
+	rt.router.GET("/users/:userId/profile/settings", rt.wrap(rt.getAccountSettings))
+	rt.router.PUT("/users/:userId/profile/settings", rt.wrap(rt.updateAccountSettings))
 	rt.router.GET("/users/:userId/stream", rt.wrap(rt.getMyStream))
 
 	// Photos routes
 	rt.router.POST("/users/:userId/photos", rt.wrap(rt.uploadPhoto))
+	rt.router.GET("/users/:userId/photos/:photosId", rt.wrap(rt.getPhoto))
 	rt.router.DELETE("/users/:userId/photos/:photosId", rt.wrap(rt.deletePhoto))
+	rt.router.GET("/users/:userId/photos/:photosId/images/:imageIndex", rt.wrap(rt.getPhotoImage))
+	rt.router.PUT("/users/:userId/photos/:photosId/settings", rt.wrap(rt.updatePhotoSettings))
+	rt.router.POST("/users/:userId/photos/:photosId/archive", rt.wrap(rt.archivePhoto))
+	rt.router.DELETE("/users/:userId/photos/:photosId/archive", rt.wrap(rt.unarchivePhoto))
+	rt.router.GET("/users/:userId/archive", rt.wrap(rt.getArchive))
+	rt.router.GET("/users/:userId/photos/:photosId/insights", rt.wrap(rt.getPhotoInsights))
+	rt.router.GET("/users/:userId/insights", rt.wrap(rt.getUserInsights))
+
+	// Trash routes
+	rt.router.GET("/users/:userId/trash", rt.wrap(rt.getTrash))
+	rt.router.DELETE("/users/:userId/trash", rt.wrap(rt.emptyTrash))
+	rt.router.DELETE("/users/:userId/trash/:photosId", rt.wrap(rt.purgePhoto))
+	rt.router.POST("/users/:userId/trash/:photosId/restore", rt.wrap(rt.restorePhoto))
+
+	// Scheduled photos routes
+	rt.router.GET("/users/:userId/scheduled", rt.wrap(rt.getScheduledPhotos))
+	rt.router.PUT("/users/:userId/scheduled/:photosId", rt.wrap(rt.reschedulePhoto))
+
+	// Resumable uploads routes
+	rt.router.POST("/users/:userId/uploads", rt.wrap(rt.createUpload))
+	rt.router.HEAD("/users/:userId/uploads/:uploadId", rt.wrap(rt.getUploadStatus))
+	rt.router.PATCH("/users/:userId/uploads/:uploadId", rt.wrap(rt.patchUpload))
+	rt.router.DELETE("/users/:userId/uploads/:uploadId", rt.wrap(rt.deleteUpload))
+
+	// Admin routes
+	rt.router.GET("/admin/users/:userId/quota", rt.wrap(rt.getUserQuota))
+	rt.router.PUT("/admin/users/:userId/quota", rt.wrap(rt.setUserQuota))
+	rt.router.DELETE("/admin/users/:userId/quota", rt.wrap(rt.deleteUserQuota))
+	rt.router.GET("/admin/blocked-hashes", rt.wrap(rt.getBlockedHashes))
+	rt.router.PUT("/admin/blocked-hashes/:hash", rt.wrap(rt.blockHash))
+	rt.router.DELETE("/admin/blocked-hashes/:hash", rt.wrap(rt.unblockHash))
+
+	// Albums routes
+	rt.router.GET("/users/:userId/albums", rt.wrap(rt.getUserAlbums))
+	rt.router.POST("/users/:userId/albums", rt.wrap(rt.createAlbum))
+	rt.router.GET("/users/:userId/albums/:albumId", rt.wrap(rt.getAlbum))
+	rt.router.PUT("/users/:userId/albums/:albumId", rt.wrap(rt.updateAlbum))
+	rt.router.DELETE("/users/:userId/albums/:albumId", rt.wrap(rt.deleteAlbum))
+	rt.router.PUT("/users/:userId/albums/:albumId/photos", rt.wrap(rt.reorderAlbumPhotos))
+	rt.router.PUT("/users/:userId/albums/:albumId/photos/:photosId", rt.wrap(rt.addPhotoToAlbum))
+	rt.router.DELETE("/users/:userId/albums/:albumId/photos/:photosId", rt.wrap(rt.removePhotoFromAlbum))
+
+	// Bookmarks routes
+	rt.router.GET("/users/:userId/bookmarks", rt.wrap(rt.getBookmarks))
+	rt.router.PUT("/users/:userId/bookmarks/:photosId", rt.wrap(rt.saveBookmark))
+	rt.router.DELETE("/users/:userId/bookmarks/:photosId", rt.wrap(rt.deleteBookmark))
+
+	// Reposts routes
+	rt.router.PUT("/users/:userId/reposts/:photosId", rt.wrap(rt.repostPhoto))
+	rt.router.DELETE("/users/:userId/reposts/:photosId", rt.wrap(rt.unrepostPhoto))
 
 	// Likes routes
 	rt.router.POST("/users/:userId/photos/:photosId/likes", rt.wrap(rt.likePhoto))
+	rt.router.DELETE("/users/:userId/photos/:photosId/likes", rt.wrap(rt.deleteMyLike))
 	rt.router.DELETE("/users/:userId/photos/:photosId/likes/:likesId", rt.wrap(rt.unlikePhoto))
 	rt.router.GET("/users/:userId/photos/:photosId/likes", rt.wrap(rt.getPhotoLikes))
+	rt.router.GET("/users/:userId/photos/:photosId/likers", rt.wrap(rt.getPhotoLikers))
+
+	// Reactions routes
+	rt.router.GET("/reactions", rt.wrap(rt.getReactionTypes))
+	rt.router.PUT("/users/:userId/photos/:photosId/reactions/:reaction", rt.wrap(rt.setReaction))
+	rt.router.DELETE("/users/:userId/photos/:photosId/reactions/:reaction", rt.wrap(rt.deleteReaction))
+	rt.router.GET("/users/:userId/photos/:photosId/reactions/:reaction", rt.wrap(rt.getPhotoReactions))
 
 	// Comments routes
 	rt.router.POST("/users/:userId/photos/:photosId/comments", rt.wrap(rt.commentPhoto))
 	rt.router.GET("/users/:userId/photos/:photosId/comments", rt.wrap(rt.getPhotoComments))
 	rt.router.DELETE("/users/:userId/photos/:photosId/comments/:commentsId", rt.wrap(rt.uncommentPhoto))
+	rt.router.PATCH("/users/:userId/photos/:photosId/comments/:commentsId", rt.wrap(rt.editComment))
+	rt.router.GET("/users/:userId/photos/:photosId/comments/:commentsId/history", rt.wrap(rt.getCommentHistory))
+	rt.router.PUT("/users/:userId/photos/:photosId/comments/:commentsId/hidden", rt.wrap(rt.hideComment))
+	rt.router.DELETE("/users/:userId/photos/:photosId/comments/:commentsId/hidden", rt.wrap(rt.unhideComment))
+	rt.router.PUT("/users/:userId/photos/:photosId/comments/:commentsId/pin", rt.wrap(rt.pinComment))
+	rt.router.DELETE("/users/:userId/photos/:photosId/comments/:commentsId/pin", rt.wrap(rt.unpinComment))
+	rt.router.POST("/users/:userId/photos/:photosId/comments/:commentsId/replies", rt.wrap(rt.replyToComment))
+	rt.router.GET("/users/:userId/photos/:photosId/comments/:commentsId/replies", rt.wrap(rt.getCommentReplies))
+	rt.router.POST("/users/:userId/photos/:photosId/comments/:commentsId/likes", rt.wrap(rt.likeComment))
+	rt.router.DELETE("/users/:userId/photos/:photosId/comments/:commentsId/likes", rt.wrap(rt.unlikeComment))
+	rt.router.PUT("/users/:userId/photos/:photosId/comments/:commentsId/reactions/:reaction", rt.wrap(rt.setCommentReaction))
+	rt.router.DELETE("/users/:userId/photos/:photosId/comments/:commentsId/reactions/:reaction", rt.wrap(rt.deleteCommentReaction))
 
 	// Follows routes
 	rt.router.POST("/users/:userId/follows/:followedId", rt.wrap(rt.followUser))
 	rt.router.DELETE("/users/:userId/follows/:followedId", rt.wrap(rt.unfollowUser))
 	rt.router.GET("/users/:userId/follows/:followedId", rt.wrap(rt.getIsFollowed))
+	rt.router.GET("/users/:userId/followers", rt.wrap(rt.getFollowers))
+	rt.router.GET("/users/:userId/following", rt.wrap(rt.getFollowing))
+	rt.router.GET("/users/:userId/relationship/:otherId", rt.wrap(rt.getRelationship))
+
+	// Follow requests routes
+	rt.router.GET("/users/:userId/follow-requests", rt.wrap(rt.getFollowRequests))
+	rt.router.PUT("/users/:userId/follow-requests/:requesterId", rt.wrap(rt.approveFollowRequest))
+	rt.router.DELETE("/users/:userId/follow-requests/:requesterId", rt.wrap(rt.rejectFollowRequest))
+	rt.router.GET("/users/:userId/sent-follow-requests", rt.wrap(rt.getSentFollowRequests))
 
 	// Ban routes
 	rt.router.POST("/users/:userId/bans/:bannedId", rt.wrap(rt.banUser))
 	rt.router.DELETE("/users/:userId/bans/:bannedId", rt.wrap(rt.unbanUser))
 	rt.router.GET("/users/:userId/bans/:bannedId", rt.wrap(rt.getIsBanned))
 
+	// Mute routes
+	rt.router.GET("/users/:userId/mutes", rt.wrap(rt.getMutes))
+	rt.router.PUT("/users/:userId/mutes/:mutedId", rt.wrap(rt.muteUser))
+	rt.router.DELETE("/users/:userId/mutes/:mutedId", rt.wrap(rt.unmuteUser))
+
 	return rt.router
 }
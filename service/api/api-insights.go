@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// defaultInsightsDays e maxInsightsDays sono il numero predefinito e massimo di giorni delle statistiche giornaliere
+const (
+	defaultInsightsDays = 30
+	maxInsightsDays     = 365
+)
+
+// dayFormat è il formato dei giorni nelle statistiche: YYYYMMDD
+const dayFormat = "20060102"
+
+// errInvalidInsightsDays indica che il parametro "days" della richiesta non è valido
+var errInvalidInsightsDays = errors.New("days must be a number from 1 to " + strconv.Itoa(maxInsightsDays))
+
+// recordView registra che user ha visto la foto photoID di ownerID. Le visualizzazioni del proprietario non contano e
+// quelle ripetute dallo stesso utente entro rt.viewsWindow contano una volta sola. Un errore viene solo registrato nel
+// log, perché non deve impedire di mostrare la foto.
+func (rt *_router) recordView(ctx reqcontext.RequestContext, user database.User, ownerID string, photoID string) {
+	if strconv.Itoa(user.ID) == ownerID {
+		return
+	}
+
+	now := globaltime.Now()
+	err := ctx.Database.RecordPhotoView(photoID, strconv.Itoa(user.ID), now.Format(timestampFormat),
+		now.Add(-rt.viewsWindow).Format(timestampFormat))
+	if err != nil {
+		ctx.Logger.WithError(err).Error("can't record photo view")
+	}
+}
+
+// parseInsightsDays legge dal parametro "days" della richiesta quanti giorni, fino a oggi compreso, includere nelle
+// statistiche giornaliere e restituisce il primo giorno
+func parseInsightsDays(r *http.Request) (time.Time, int, error) {
+	days := defaultInsightsDays
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > maxInsightsDays {
+			return time.Time{}, 0, errInvalidInsightsDays
+		}
+	}
+
+	now := globaltime.Now()
+	first := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1-days)
+	return first, days, nil
+}
+
+// fillInsightsDays completa le statistiche giornaliere con i giorni senza interazioni, così che Days contenga tutti i
+// giorni da first in ordine
+func fillInsightsDays(insights *database.Insights, first time.Time, days int) {
+	byDay := make(map[string]database.InsightsDay, len(insights.Days))
+	for _, day := range insights.Days {
+		byDay[day.Day] = day
+	}
+
+	insights.Days = make([]database.InsightsDay, 0, days)
+	for i := 0; i < days; i++ {
+		key := first.AddDate(0, 0, i).Format(dayFormat)
+		day, ok := byDay[key]
+		if !ok {
+			day = database.InsightsDay{Day: key}
+		}
+		insights.Days = append(insights.Days, day)
+	}
+}
+
+// writeInsights risponde con le statistiche, completate con i giorni senza interazioni
+func writeInsights(w http.ResponseWriter, insights database.Insights, first time.Time, days int) {
+	fillInsightsDays(&insights, first, days)
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(insights)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// getPhotoInsights restituisce al proprietario le statistiche di una sua foto: visualizzazioni, like e commenti, in
+// totale e giorno per giorno
+func (rt *_router) getPhotoInsights(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photo, ok := loadOwnPhoto(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+
+	first, days, err := parseInsightsDays(r)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	insights, err := ctx.Database.GetPhotoInsights(strconv.Itoa(photo.ID), first.Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error retrieving insights of photo %d: %v", photo.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeInsights(w, insights, first, days)
+}
+
+// getUserInsights restituisce all'utente autenticato le statistiche di tutte le sue foto: visualizzazioni, like e
+// commenti, in totale e giorno per giorno
+func (rt *_router) getUserInsights(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	first, days, err := parseInsightsDays(r)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	insights, err := ctx.Database.GetUserInsights(strconv.Itoa(user.ID), first.Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error retrieving insights of user %d: %v", user.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writeInsights(w, insights, first, days)
+}
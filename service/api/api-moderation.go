@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxPinnedComments è il numero massimo di commenti che il proprietario può fissare in alto su una foto
+const maxPinnedComments = 3
+
+// errInvalidCommentPolicy indica che la scelta su chi può commentare una foto non è valida
+var errInvalidCommentPolicy = errors.New("comment_policy must be everyone, followers or off")
+
+// checkCommentPolicy verifica che user possa commentare la foto photoID secondo la scelta del proprietario. In caso di
+// errore scrive la risposta e restituisce false.
+func checkCommentPolicy(w http.ResponseWriter, ctx reqcontext.RequestContext, user database.User, photoID string) bool {
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	switch photo.CommentPolicy {
+	case database.CommentsOff:
+		http.Error(w, "Forbidden: comments are turned off for this photo", http.StatusForbidden)
+		return false
+	case database.CommentsFollowers:
+		if photo.UserID == user.ID {
+			return true
+		}
+		follows, err := ctx.Database.IsFollowed(strconv.Itoa(user.ID), strconv.Itoa(photo.UserID))
+		if err != nil {
+			log.Printf("Error checking follow: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return false
+		}
+		if !follows {
+			http.Error(w, "Forbidden: only followers can comment this photo", http.StatusForbidden)
+			return false
+		}
+	}
+
+	return true
+}
+
+// loadModeratedComment autentica il proprietario della foto photosId e restituisce il commento commentsId del
+// percorso, che deve appartenere alla foto e non essere eliminato. In caso di errore scrive la risposta e restituisce
+// false.
+func loadModeratedComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) (database.Comment, bool) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return database.Comment{}, false
+	}
+
+	if _, ok := loadOwnPhoto(w, ps, ctx, user); !ok {
+		return database.Comment{}, false
+	}
+
+	comment, ok := loadThreadComment(w, ps, ctx, user)
+	if !ok {
+		return comment, false
+	}
+	if comment.DeletedAt != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// hideComment permette al proprietario di una foto di nascondere un commento: resta visibile solo a lui e all'autore
+func (rt *_router) hideComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	comment, ok := loadModeratedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.HideComment(strconv.Itoa(comment.ID), globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error hiding comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unhideComment permette al proprietario di una foto di rendere di nuovo visibile a tutti un commento nascosto
+func (rt *_router) unhideComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	comment, ok := loadModeratedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.UnhideComment(strconv.Itoa(comment.ID))
+	if err != nil {
+		log.Printf("Error unhiding comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pinComment permette al proprietario di una foto di fissare in alto un commento principale, fino a maxPinnedComments
+// commenti per foto
+func (rt *_router) pinComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	comment, ok := loadModeratedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	if comment.ParentID != nil {
+		http.Error(w, "Bad Request: only top-level comments can be pinned", http.StatusBadRequest)
+		return
+	}
+	if comment.Hidden {
+		http.Error(w, "Conflict: hidden comments cannot be pinned", http.StatusConflict)
+		return
+	}
+
+	if !comment.Pinned {
+		pinned, err := ctx.Database.CountPinnedComments(strconv.Itoa(comment.PhotoId))
+		if err != nil {
+			log.Printf("Error counting pinned comments: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if pinned >= maxPinnedComments {
+			http.Error(w, "Conflict: at most "+strconv.Itoa(maxPinnedComments)+" comments can be pinned",
+				http.StatusConflict)
+			return
+		}
+	}
+
+	err := ctx.Database.PinComment(strconv.Itoa(comment.ID), globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error pinning comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unpinComment permette al proprietario di una foto di togliere un commento da quelli fissati in alto
+func (rt *_router) unpinComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	comment, ok := loadModeratedComment(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.UnpinComment(strconv.Itoa(comment.ID))
+	if err != nil {
+		log.Printf("Error unpinning comment %d: %v", comment.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// mutesPage è una pagina degli utenti silenziati
+type mutesPage struct {
+	Mutes      []database.Mute `json:"mutes"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// muteUser silenzia un utente per l'utente autenticato. A differenza del ban non toglie i follow e l'utente silenziato
+// non se ne accorge: semplicemente le sue foto e i suoi commenti non vengono più mostrati.
+func (rt *_router) muteUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	mutedID := ps.ByName("mutedId")
+	if mutedID == strconv.Itoa(user.ID) {
+		http.Error(w, "Bad Request: you cannot mute yourself", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := ctx.Database.IsPrivate(mutedID); errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving user %s: %v", mutedID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err := ctx.Database.MuteUser(strconv.Itoa(user.ID), mutedID, globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error muting user %s: %v", mutedID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unmuteUser toglie il silenziamento di un utente da parte dell'utente autenticato
+func (rt *_router) unmuteUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	mutedID := ps.ByName("mutedId")
+	err := ctx.Database.UnmuteUser(strconv.Itoa(user.ID), mutedID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "Mute not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error unmuting user %s: %v", mutedID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getMutes restituisce una pagina degli utenti silenziati dall'utente autenticato, dall'ultimo silenziato
+func (rt *_router) getMutes(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Un elemento in più indica se esiste la pagina successiva
+	mutes, err := ctx.Database.GetMutesByUserID(strconv.Itoa(user.ID), cursor, limit+1)
+	if err != nil {
+		log.Printf("Error retrieving mutes: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	page := mutesPage{Mutes: []database.Mute{}}
+	if len(mutes) > limit {
+		mutes = mutes[:limit]
+		page.NextCursor = strconv.Itoa(mutes[limit-1].ID)
+	}
+	page.Mutes = append(page.Mutes, mutes...)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(page)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -1,18 +1,42 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
+	"unicode/utf8"
 
 	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
 	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
 	"github.com/julienschmidt/httprouter"
 )
 
+// maxPostImages è il numero massimo di immagini in un singolo post
+const maxPostImages = 10
+
+// maxCommentLength è il numero massimo di caratteri del testo di un commento
+const maxCommentLength = 2200
+
+// validateCommentText verifica il testo di un commento, sia alla creazione sia alla modifica: non può essere vuoto o
+// di soli spazi e non può superare maxCommentLength caratteri
+func validateCommentText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("the comment cannot be empty")
+	}
+	if utf8.RuneCountInString(text) > maxCommentLength {
+		return fmt.Errorf("the comment cannot be longer than %d characters", maxCommentLength)
+	}
+	return nil
+}
+
+// uploadPhoto crea un nuovo post con una o più immagini, inviate come parti "image" di un form multipart
 func (rt *_router) uploadPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Ottenere l'ID dell'utente dalla richiesta
 	userID := ps.ByName("userId")
@@ -51,55 +75,293 @@ func (rt *_router) uploadPhoto(w http.ResponseWriter, r *http.Request, ps httpro
 		return
 	}
 
-	file, _, err := r.FormFile("image")
+	// Un post può contenere fino a maxPostImages immagini, nell'ordine delle parti "image"
+	files := r.MultipartForm.File["image"]
+	if len(files) == 0 || len(files) > maxPostImages {
+		http.Error(w, "Bad Request: a post needs from 1 to "+strconv.Itoa(maxPostImages)+" images", http.StatusBadRequest)
+		log.Println("Wrong number of images in form data:", len(files))
+		return
+	}
+
+	// Leggi i dati di tutti i file prima di salvare, così il post viene creato solo se ogni immagine è valida
+	images := make([][]byte, 0, len(files))
+	for _, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			log.Println("Error retrieving file from form data:", err)
+			return
+		}
+
+		imageData, err := ioutil.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			log.Println("Error reading file contents:", err)
+			return
+		}
+		if len(imageData) == 0 {
+			http.Error(w, "Bad Request: empty image", http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Received image data length: %d", len(imageData))
+		images = append(images, imageData)
+	}
+
+	photo, err := rt.createPhoto(ctx, user, newPhoto{
+		Images:     images,
+		Caption:    r.FormValue("caption"),
+		Visibility: r.FormValue("visibility"),
+		PublishAt:  r.FormValue("publish_at"),
+	})
 	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		log.Println("Error retrieving file from form data:", err)
+		writeCreatePhotoError(w, err)
 		return
 	}
-	defer file.Close()
 
-	// Leggi i dati del file in un byte slice
-	imageData, err := ioutil.ReadAll(file)
+	// Creare la risposta JSON contenente i dettagli della foto
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(photo)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		log.Println("Error reading file contents:", err)
+		log.Println("Error encoding JSON response:", err)
 		return
 	}
 
-	log.Printf("Received image data length: %d", len(imageData))
+	// Log per mostrare che la risposta JSON è stata inviata correttamente
+	log.Println("JSON response sent successfully")
+}
+
+// newPhoto contiene i dati di un nuovo post, presi dal form di uploadPhoto o dai metadati di un upload ripristinabile
+// Se Visibility è vuota viene usata la visibilità predefinita dell'utente; se PublishAt (RFC 3339) non è vuoto il post
+// viene programmato e pubblicato in quel momento da publishScheduled.
+type newPhoto struct {
+	Images     [][]byte
+	Caption    string
+	Visibility string
+	PublishAt  string
+}
+
+// createdPhoto è il post appena creato da createPhoto. DuplicateOf contiene gli ID delle foto dell'utente quasi
+// identiche al nuovo post, che viene pubblicato comunque se la configurazione si limita ad avvisare dei duplicati.
+type createdPhoto struct {
+	database.Photo
+	DuplicateOf []int `json:"duplicate_of,omitempty"`
+}
 
-	// Salvataggio dell'immagine nel database e ottenimento dell'ID della foto
-	timestamp := time.Now().Format("20060102150405") // Formato timestamp: YYYYMMDDHHmmSS
-	photoID, err := ctx.Database.SetPhoto(userID, imageData, timestamp)
+// createPhoto è la pipeline di pubblicazione di un nuovo post di user, comune a uploadPhoto e agli upload
+// ripristinabili. Gli errori dovuti al contenuto del post vanno mostrati al client con writeCreatePhotoError.
+func (rt *_router) createPhoto(ctx reqcontext.RequestContext, user database.User, post newPhoto) (createdPhoto, error) {
+	if post.Visibility == "" {
+		defaultVisibility, err := ctx.Database.GetDefaultVisibility(strconv.Itoa(user.ID))
+		if err != nil {
+			return createdPhoto{}, fmt.Errorf("reading default visibility: %w", err)
+		}
+		post.Visibility = defaultVisibility
+	} else if !database.IsValidVisibility(post.Visibility) {
+		return createdPhoto{}, errInvalidVisibility
+	}
+
+	// Un post programmato ha come timestamp il momento della pubblicazione
+	timestamp := globaltime.Now().Format(timestampFormat)
+	var publishAt *string
+	if post.PublishAt != "" {
+		var err error
+		timestamp, err = parsePublishAt(post.PublishAt)
+		if err != nil {
+			return createdPhoto{}, err
+		}
+		publishAt = &timestamp
+	}
+
+	// Le immagini quasi identiche a una bloccata dagli amministratori non possono essere pubblicate
+	hashes := hashImages(post.Images)
+	err := rt.checkBlockedImages(ctx.Database, hashes)
+	if err != nil {
+		return createdPhoto{}, err
+	}
+
+	// Le menzioni @username nella didascalia vengono risolte subito
+	mentions, err := resolveMentions(ctx.Database, user, post.Caption)
+	if err != nil {
+		return createdPhoto{}, err
+	}
+
+	// Il post deve rientrare nella quota dell'utente; il lock impedisce che due upload contemporanei la superino insieme
+	var size int64
+	for _, image := range post.Images {
+		size += int64(len(image))
+	}
+	lock := rt.quotaLock(user.ID)
+	lock.Lock()
+	defer lock.Unlock()
+	err = rt.checkQuota(ctx.Database, user.ID, size)
+	if err != nil {
+		return createdPhoto{}, err
+	}
+
+	// Un post quasi identico a una foto già pubblicata dall'utente viene rifiutato o segnalato, secondo la configurazione
+	duplicates, err := rt.findDuplicates(ctx.Database, user, hashes)
 	if err != nil {
+		return createdPhoto{}, err
+	}
+	if len(duplicates) > 0 && rt.duplicatesMode == duplicatesReject {
+		return createdPhoto{}, errDuplicatePhoto
+	}
+
+	// Salvataggio delle immagini nel database e ottenimento dell'ID della foto
+	photoID, err := ctx.Database.SetPhoto(strconv.Itoa(user.ID), post.Images, hashes, post.Caption, mentions, post.Visibility, timestamp, publishAt != nil)
+	if err != nil {
+		return createdPhoto{}, fmt.Errorf("saving photo: %w", err)
+	}
+
+	// Costruisci l'oggetto Photo da restituire come risposta JSON
+	return createdPhoto{
+		Photo: database.Photo{
+			ID:            int(photoID),
+			UserID:        user.ID,
+			ImageData:     post.Images[0],
+			Timestamp:     timestamp,
+			Caption:       post.Caption,
+			Mentions:      mentions,
+			NumImages:     len(post.Images),
+			Visibility:    post.Visibility,
+			PublishAt:     publishAt,
+			CommentPolicy: database.CommentsEveryone,
+		},
+		DuplicateOf: duplicates,
+	}, nil
+}
+
+// writeCreatePhotoError risponde al client con l'errore restituito da createPhoto
+func writeCreatePhotoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMentionBanned):
+		http.Error(w, "Forbidden: a mentioned user has banned you", http.StatusForbidden)
+	case errors.Is(err, errInvalidVisibility), errors.Is(err, errInvalidPublishAt):
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errQuotaExceeded), errors.Is(err, errBlockedImage):
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+	case errors.Is(err, errDuplicatePhoto):
+		http.Error(w, "Conflict: "+err.Error(), http.StatusConflict)
+	default:
+		log.Println("Error creating photo:", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		log.Println("Error saving photo and retrieving ID:", err)
+	}
+}
+
+// photoDetailsComments è il numero di commenti restituiti insieme ai dettagli di una foto
+const photoDetailsComments = 20
+
+// getPhoto restituisce i dettagli di una singola foto: proprietario, conteggi di like e commenti, se l'utente ha messo
+// like e la prima pagina di commenti
+func (rt *_router) getPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Costruisci l'oggetto Photo da restituire come risposta JSON
-	photo := database.Photo{
-		ID:        int(photoID),
-		UserID:    user.ID, // Utilizzo user.ID come ID dell'utente autenticato
-		ImageData: imageData,
-		Timestamp: timestamp,
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	photoID := ps.ByName("photosId")
+
+	if !checkPhotoAccess(w, ctx, user, userID, photoID) {
+		return
+	}
+
+	details, err := ctx.Database.GetPhotoDetails(photoID, strconv.Itoa(user.ID))
+	if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	comments, err := ctx.Database.GetFirstCommentsByPhotoID(photoID, strconv.Itoa(user.ID), photoDetailsComments)
+	if err != nil {
+		log.Printf("Error retrieving comments: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if comments == nil {
+		comments = []database.Comment{}
+	}
+
+	rt.recordView(ctx, user, userID, photoID)
+
+	response := struct {
+		database.PhotoDetails
+		Comments []database.Comment `json:"comments"`
+	}{
+		PhotoDetails: details,
+		Comments:     comments,
 	}
 
-	// Creare la risposta JSON contenente i dettagli della foto
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(photo)
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		log.Println("Error encoding JSON response:", err)
 		return
 	}
+}
 
-	// Log per mostrare che la risposta JSON è stata inviata correttamente
-	log.Println("JSON response sent successfully")
+// getPhotoImage restituisce una singola immagine di un post, indicata dalla sua posizione (a partire da 0)
+func (rt *_router) getPhotoImage(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	photoID := ps.ByName("photosId")
+	position, err := strconv.Atoi(ps.ByName("imageIndex"))
+	if err != nil || position < 0 {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !checkPhotoAccess(w, ctx, user, userID, photoID) {
+		return
+	}
+
+	image, err := ctx.Database.GetPhotoImage(photoID, position)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving image %d of photo %s: %v", position, photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	rt.recordView(ctx, user, userID, photoID)
+
+	w.Header().Set("Content-Type", http.DetectContentType(image))
+	if _, err := w.Write(image); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
 }
 
-// deletePhotoHandler elimina una foto dal sistema di archiviazione locale e dal database
+// deletePhotoHandler sposta una foto nel cestino del proprietario, da cui può essere ripristinata finché non viene
+// eliminata definitivamente
 
 func (rt *_router) deletePhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 	// Ottenere l'ID dell'utente e l'ID della foto dalla richiesta
@@ -133,7 +395,10 @@ func (rt *_router) deletePhoto(w http.ResponseWriter, r *http.Request, ps httpro
 
 	// Verificare se l'utente possiede la foto
 	photo, err := ctx.Database.GetPhotoByID(photoID)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || (err == nil && photo.DeletedAt != nil) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		log.Printf("Internal Server Error: Failed to retrieve photo with photoId: %s\n", photoID)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -145,9 +410,12 @@ func (rt *_router) deletePhoto(w http.ResponseWriter, r *http.Request, ps httpro
 		return
 	}
 
-	// Eliminare la foto dal database
-	err = ctx.Database.DeletePhoto(photoID)
-	if err != nil {
+	// Spostare la foto nel cestino: verrà eliminata definitivamente da purgeTrash, allo scadere di rt.trashRetention
+	err = ctx.Database.TrashPhoto(photoID, globaltime.Now().Format(timestampFormat))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		log.Printf("Internal Server Error: Failed to delete photo with photoId: %s\n", photoID)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -184,8 +452,13 @@ func (rt *_router) likePhoto(w http.ResponseWriter, r *http.Request, ps httprout
 		return
 	}
 
-	// Aggiungere un like alla foto nel database
-	err = ctx.Database.SetLike(userID, photoID)
+	// Si può mettere like solo alle foto che si possono vedere
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), photoID) {
+		return
+	}
+
+	// Il like è la reazione predefinita: sostituisce l'eventuale reazione di altro tipo dell'utente
+	err = ctx.Database.SetReaction(userID, photoID, database.DefaultReaction, globaltime.Now().Format(timestampFormat))
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -224,13 +497,22 @@ func (rt *_router) unlikePhoto(w http.ResponseWriter, r *http.Request, ps httpro
 
 	// Verificare se l'utente è chi ha messo il like
 	like, err := ctx.Database.GetLikeByID(likeID)
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "Like not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	log.Printf("likeID: %d, userID: %d, photoID: %d", like.ID, like.UserID, like.PhotoID)
 
+	// Le altre reazioni e i like di altre foto non si tolgono da qui
+	if strconv.Itoa(like.PhotoID) != photoID {
+		http.Error(w, "Like not found", http.StatusNotFound)
+		return
+	}
+
 	if like.UserID != user.ID {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -266,12 +548,16 @@ func (rt *_router) getPhotoLikes(w http.ResponseWriter, r *http.Request, ps http
 	}
 
 	// Autentica l'utente utilizzando il token
-	_, err = reqcontext.AuthenticateUser(token, ctx.Database)
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	if !checkPhotoAccess(w, ctx, user, userID, photoID) {
+		return
+	}
+
 	// Ottenere i likes della foto dal database
 	likes, err := ctx.Database.GetLikesByPhotoID(photoID)
 	if err != nil {
@@ -288,6 +574,60 @@ func (rt *_router) getPhotoLikes(w http.ResponseWriter, r *http.Request, ps http
 	}
 }
 
+// getPhotoLikers restituisce una pagina degli utenti che hanno messo like a una foto, dal più recente, con i loro
+// username e i rapporti di follow con l'utente autenticato
+func (rt *_router) getPhotoLikers(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), photoID) {
+		return
+	}
+
+	writeReactionsPage(w, r, ctx, user, photoID, database.DefaultReaction)
+}
+
+// deleteMyLike toglie il like dell'utente autenticato a una foto senza doverne conoscere l'ID
+func (rt *_router) deleteMyLike(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Come con l'ID del like, il like si può togliere anche se la foto non è più visibile
+	photoID := ps.ByName("photosId")
+	err = ctx.Database.DeleteReaction(strconv.Itoa(user.ID), photoID, database.DefaultReaction)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "Like not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting like to photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 // commentPhotoHandler aggiunge un commento a una foto nel database
 func (rt *_router) commentPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 
@@ -304,8 +644,7 @@ func (rt *_router) commentPhoto(w http.ResponseWriter, r *http.Request, ps httpr
 		return
 	}
 
-	// ID della foto dalla richiesta , user che commenta e timestamp
-	timestamp := time.Now().Format("20060102150405") // Formato timestamp: YYYYMMDDHHmmSS
+	// ID della foto dalla richiesta e user che commenta
 	photoID := ps.ByName("photosId")
 	userID := strconv.Itoa(user.ID)
 	log.Printf("userID: %s, photoId: %s", userID, photoID)
@@ -316,12 +655,46 @@ func (rt *_router) commentPhoto(w http.ResponseWriter, r *http.Request, ps httpr
 		return
 	}
 
+	// Si può commentare solo le foto che si possono vedere
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), photoID) {
+		return
+	}
+
+	rt.writeNewComment(w, r, ctx, user, photoID, nil)
+}
+
+// writeNewComment salva sulla foto photoID il commento nel campo "comment" della richiesta, come risposta al commento
+// principale parentID se non è nil, e risponde con il commento creato
+func (rt *_router) writeNewComment(w http.ResponseWriter, r *http.Request, ctx reqcontext.RequestContext, user database.User, photoID string, parentID *int) {
+	timestamp := globaltime.Now().Format(timestampFormat)
+	userID := strconv.Itoa(user.ID)
+
+	// Il proprietario della foto può chiudere i commenti o riservarli ai follower
+	if !checkCommentPolicy(w, ctx, user, photoID) {
+		return
+	}
+
 	// Ottenere il testo del commento dalla richiesta
 	comment := r.FormValue("comment")
 	log.Printf("comment: %s", comment)
+	if err := validateCommentText(comment); err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Risolvere le menzioni @username presenti nel commento
+	mentions, err := resolveMentions(ctx.Database, user, comment)
+	if errors.Is(err, errMentionBanned) {
+		http.Error(w, "Forbidden: a mentioned user has banned you", http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		log.Println("Error resolving comment mentions:", err)
+		return
+	}
 
 	// Aggiungere il commento alla foto nel database
-	commentID, err := ctx.Database.SetComment(userID, photoID, comment, timestamp)
+	commentID, err := ctx.Database.SetComment(userID, photoID, parentID, comment, mentions, timestamp)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		log.Println("Error saving comment and retrieving ID:", err)
@@ -333,9 +706,12 @@ func (rt *_router) commentPhoto(w http.ResponseWriter, r *http.Request, ps httpr
 	commentResponse := database.Comment{
 		ID:        int(commentID),
 		UserId:    user.ID,
+		Username:  user.Username,
 		PhotoId:   photoIDInt,
 		Text:      comment,
 		Timestamp: timestamp,
+		Mentions:  mentions,
+		ParentID:  parentID,
 	}
 
 	// Creare la risposta JSON contenente i dettagli della foto
@@ -376,20 +752,31 @@ func (rt *_router) uncommentPhoto(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	// Verificare se l'utente è chi ha scritto il commento
-	comment, err := ctx.Database.GetCommentByID(commentID)
+	// Il commento deve essere della foto; di un commento già eliminato resta solo il segnaposto
+	comment, err := ctx.Database.GetCommentByID(commentID, userID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) ||
+		(err == nil && (comment.DeletedAt != nil || strconv.Itoa(comment.PhotoId) != photoID)) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Verificare se l'utente è chi ha scritto il commento o il proprietario della foto, che può moderarne i commenti
+	photo, err := ctx.Database.GetPhotoByID(photoID)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	if comment.UserId != user.ID {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	if comment.UserId != user.ID && photo.UserID != user.ID {
+		http.Error(w, "Forbidden: only the author or the owner of the photo can delete a comment", http.StatusForbidden)
 		return
 	}
 
-	// Rimuovere il commento dalla foto nel database
-	err = ctx.Database.DeleteComment(commentID)
+	// Rimuovere il commento dalla foto nel database; se ha risposte ne resta un segnaposto
+	err = ctx.Database.DeleteComment(commentID, globaltime.Now().Format(timestampFormat))
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -399,7 +786,16 @@ func (rt *_router) uncommentPhoto(w http.ResponseWriter, r *http.Request, ps htt
 	w.WriteHeader(http.StatusOK)
 }
 
-// getPhotoCommentsHandler ottiene i commenti di una foto dal database
+// commentsPage è una pagina dei commenti principali di una foto. La prima pagina contiene, prima degli altri, anche i
+// commenti fissati in alto, che non rientrano nel limite della pagina. Fino alla versione 1.x delle API la risposta era
+// l'array dei commenti: il passaggio a questo oggetto è una modifica incompatibile, segnalata in doc/api.yaml.
+type commentsPage struct {
+	Comments   []database.Comment `json:"comments"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// getPhotoCommentsHandler ottiene una pagina dei commenti di una foto dal database, dal meno recente o, con il
+// parametro order=newest, dal più recente
 func (rt *_router) getPhotoComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
 
 	// Ottenere l'ID dell'utente e l'ID della foto dalla richiesta
@@ -419,22 +815,62 @@ func (rt *_router) getPhotoComments(w http.ResponseWriter, r *http.Request, ps h
 	}
 
 	// Autentica l'utente utilizzando il token
-	_, err = reqcontext.AuthenticateUser(token, ctx.Database)
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Ottenere i commenti della foto dal database
-	comments, err := ctx.Database.GetCommentsByPhotoID(photoID)
+	if !checkPhotoAccess(w, ctx, user, userID, photoID) {
+		return
+	}
+
+	cursor, limit, err := parsePage(r)
 	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var newestFirst bool
+	switch r.URL.Query().Get("order") {
+	case "", "oldest":
+	case "newest":
+		newestFirst = true
+	default:
+		http.Error(w, "Bad Request: order must be oldest or newest", http.StatusBadRequest)
+		return
+	}
+
+	page := commentsPage{Comments: []database.Comment{}}
+
+	// I commenti fissati in alto vengono restituiti solo all'inizio della prima pagina
+	if cursor == 0 {
+		pinned, err := ctx.Database.GetPinnedCommentsByPhotoID(photoID, strconv.Itoa(user.ID))
+		if err != nil {
+			log.Printf("Error retrieving pinned comments of photo %s: %v", photoID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		page.Comments = append(page.Comments, pinned...)
+	}
+
+	// Ottenere i commenti della foto dal database; un elemento in più indica se esiste la pagina successiva
+	comments, err := ctx.Database.GetCommentsByPhotoID(photoID, strconv.Itoa(user.ID), newestFirst, cursor, limit+1)
+	if err != nil {
+		log.Printf("Error retrieving comments of photo %s: %v", photoID, err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	if len(comments) > limit {
+		comments = comments[:limit]
+		page.NextCursor = strconv.Itoa(comments[limit-1].ID)
+	}
+	page.Comments = append(page.Comments, comments...)
+
 	// Creare la risposta JSON contenente i commenti della foto
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(comments)
+	err = json.NewEncoder(w).Encode(page)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
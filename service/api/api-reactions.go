@@ -0,0 +1,191 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// reactionPattern è il formato dei tipi di reazione configurabili, usati anche nei percorsi delle API
+var reactionPattern = regexp.MustCompile(`^[a-z_]{1,20}$`)
+
+// reactionsPage è una pagina degli utenti che hanno reagito a una foto con un tipo di reazione
+type reactionsPage struct {
+	Reactions  []database.Reaction `json:"reactions"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// validateReactionTypes verifica che i tipi di reazione configurati siano validi, senza ripetizioni e comprendano
+// database.DefaultReaction
+func validateReactionTypes(types []string) error {
+	seen := make(map[string]bool, len(types))
+	for _, reaction := range types {
+		if !reactionPattern.MatchString(reaction) {
+			return fmt.Errorf("reaction type %q must be 1 to 20 lowercase letters or underscores", reaction)
+		}
+		if seen[reaction] {
+			return fmt.Errorf("reaction type %q is repeated", reaction)
+		}
+		seen[reaction] = true
+	}
+	if !seen[database.DefaultReaction] {
+		return fmt.Errorf("reaction types must include %q", database.DefaultReaction)
+	}
+	return nil
+}
+
+// loadReactionParam restituisce il tipo di reazione reaction del percorso se è tra quelli configurati; altrimenti
+// risponde con 400 e restituisce false
+func (rt *_router) loadReactionParam(w http.ResponseWriter, ps httprouter.Params) (string, bool) {
+	reaction := ps.ByName("reaction")
+	for _, known := range rt.reactionTypes {
+		if reaction == known {
+			return reaction, true
+		}
+	}
+	http.Error(w, "Bad Request: unknown reaction", http.StatusBadRequest)
+	return reaction, false
+}
+
+// loadReactedPhoto autentica l'utente e verifica che possa vedere la foto photosId del percorso e che il tipo di
+// reazione sia tra quelli configurati. In caso di errore scrive la risposta e restituisce false.
+func (rt *_router) loadReactedPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) (database.User, string, bool) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return database.User{}, "", false
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, "", false
+	}
+
+	reaction, ok := rt.loadReactionParam(w, ps)
+	if !ok {
+		return user, reaction, false
+	}
+
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), ps.ByName("photosId")) {
+		return user, reaction, false
+	}
+
+	return user, reaction, true
+}
+
+// getReactionTypes restituisce i tipi di reazione che si possono mettere alle foto e ai commenti
+func (rt *_router) getReactionTypes(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	if _, err := reqcontext.AuthenticateUser(token, ctx.Database); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(struct {
+		Reactions []string `json:"reactions"`
+		Default   string   `json:"default"`
+	}{rt.reactionTypes, database.DefaultReaction})
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// setReaction imposta la reazione dell'utente autenticato a una foto, sostituendo quella che aveva già messo
+func (rt *_router) setReaction(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, reaction, ok := rt.loadReactedPhoto(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	err := ctx.Database.SetReaction(strconv.Itoa(user.ID), photoID, reaction, globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error setting reaction to photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteReaction toglie la reazione dell'utente autenticato a una foto, se è del tipo indicato
+func (rt *_router) deleteReaction(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, reaction, ok := rt.loadReactedPhoto(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	err := ctx.Database.DeleteReaction(strconv.Itoa(user.ID), photoID, reaction)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Reaction not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting reaction to photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getPhotoReactions restituisce una pagina degli utenti che hanno reagito a una foto con il tipo di reazione indicato,
+// dal più recente
+func (rt *_router) getPhotoReactions(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, reaction, ok := rt.loadReactedPhoto(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	writeReactionsPage(w, r, ctx, user, ps.ByName("photosId"), reaction)
+}
+
+// writeReactionsPage risponde con la pagina richiesta degli utenti che hanno reagito alla foto photoID con il tipo di
+// reazione reaction, dal più recente, indicando per ognuno i rapporti di follow con user
+func writeReactionsPage(w http.ResponseWriter, r *http.Request, ctx reqcontext.RequestContext, user database.User, photoID string, reaction string) {
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Un elemento in più indica se esiste la pagina successiva
+	reactions, err := ctx.Database.GetReactionsByPhotoID(photoID, strconv.Itoa(user.ID), reaction, cursor, limit+1)
+	if err != nil {
+		log.Printf("Error retrieving reactions to photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	page := reactionsPage{Reactions: []database.Reaction{}}
+	if len(reactions) > limit {
+		reactions = reactions[:limit]
+		page.NextCursor = strconv.Itoa(reactions[limit-1].ID)
+	}
+	page.Reactions = append(page.Reactions, reactions...)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(page)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// repliesPage è una pagina delle risposte a un commento
+type repliesPage struct {
+	Replies    []database.Comment `json:"replies"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// loadThreadComment restituisce il commento commentsId del percorso se appartiene alla foto photosId di userId ed è
+// visibile a user; altrimenti risponde con 404 e restituisce false. Il proprietario della foto deve essere già stato
+// verificato, ad esempio con checkPhotoAccess.
+func loadThreadComment(w http.ResponseWriter, ps httprouter.Params, ctx reqcontext.RequestContext, user database.User) (database.Comment, bool) {
+	commentID := ps.ByName("commentsId")
+	comment, err := ctx.Database.GetCommentByID(commentID, strconv.Itoa(user.ID))
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) ||
+		(err == nil && strconv.Itoa(comment.PhotoId) != ps.ByName("photosId")) ||
+		(err == nil && comment.Hidden && comment.UserId != user.ID && strconv.Itoa(user.ID) != ps.ByName("userId")) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return comment, false
+	} else if err != nil {
+		log.Printf("Error retrieving comment %s: %v", commentID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return comment, false
+	}
+	return comment, true
+}
+
+// replyToComment aggiunge una risposta a un commento di una foto. Le discussioni hanno un solo livello: chi risponde
+// a una risposta risponde al suo commento principale.
+func (rt *_router) replyToComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), photoID) {
+		return
+	}
+
+	parent, ok := loadThreadComment(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+	parentID := parent.ID
+	if parent.ParentID != nil {
+		parentID = *parent.ParentID
+	} else if parent.DeletedAt != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+
+	rt.writeNewComment(w, r, ctx, user, photoID, &parentID)
+}
+
+// getCommentReplies restituisce una pagina delle risposte a un commento principale di una foto, dalla meno recente
+func (rt *_router) getCommentReplies(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !checkPhotoAccess(w, ctx, user, ps.ByName("userId"), ps.ByName("photosId")) {
+		return
+	}
+
+	parent, ok := loadThreadComment(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Un elemento in più indica se esiste la pagina successiva
+	replies, err := ctx.Database.GetRepliesByCommentID(strconv.Itoa(parent.ID), strconv.Itoa(user.ID), cursor, limit+1)
+	if err != nil {
+		log.Printf("Error retrieving replies to comment %d: %v", parent.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	page := repliesPage{Replies: []database.Comment{}}
+	if len(replies) > limit {
+		replies = replies[:limit]
+		page.NextCursor = strconv.Itoa(replies[limit-1].ID)
+	}
+	page.Replies = append(page.Replies, replies...)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(page)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+)
+
+// parentOf restituisce il commento principale di comment, per i messaggi dei test
+func parentOf(comment database.Comment) string {
+	if comment.ParentID == nil {
+		return "none"
+	}
+	return strconv.Itoa(*comment.ParentID)
+}
+
+func TestReplyToCommentReroots(t *testing.T) {
+	db := newTestDatabase(t)
+	h := newTestRouter(t, db)
+	owner := newTestUser(t, db, "owner")
+	other := newTestUser(t, db, "other")
+
+	newPhoto := func(t *testing.T) string {
+		t.Helper()
+		id, err := db.SetPhoto(strconv.Itoa(owner.ID), [][]byte{[]byte("image")}, []string{""}, "", nil,
+			database.VisibilityPublic, "20260101120000", false)
+		if err != nil {
+			t.Fatalf("creating photo: %v", err)
+		}
+		return strconv.FormatInt(id, 10)
+	}
+	photo := newPhoto(t)
+	commentsPath := "/users/" + strconv.Itoa(owner.ID) + "/photos/" + photo + "/comments/"
+
+	topID, err := db.SetComment(strconv.Itoa(other.ID), photo, nil, "primo", nil, "20260101120000")
+	if err != nil {
+		t.Fatalf("commenting: %v", err)
+	}
+	top := int(topID)
+
+	// reply risponde a commentID come user e restituisce lo stato e, se la risposta è stata creata, il commento
+	reply := func(t *testing.T, user database.User, path string, commentID int) (int, database.Comment) {
+		t.Helper()
+		body := url.Values{"comment": {"risposta"}}.Encode()
+		w := serveAs(h, user, http.MethodPost, path+strconv.Itoa(commentID)+"/replies", strings.NewReader(body),
+			"application/x-www-form-urlencoded")
+		var comment database.Comment
+		if w.Code == http.StatusOK {
+			err := json.NewDecoder(w.Body).Decode(&comment)
+			if err != nil {
+				t.Fatalf("decoding reply: %v", err)
+			}
+		}
+		return w.Code, comment
+	}
+
+	status, first := reply(t, owner, commentsPath, top)
+	if status != http.StatusOK || first.ParentID == nil || *first.ParentID != top {
+		t.Fatalf("reply to the top-level comment: got status %d and parent %s, want 200 and %d", status,
+			parentOf(first), top)
+	}
+
+	// Chi risponde a una risposta risponde al commento principale: le discussioni hanno un solo livello
+	status, second := reply(t, other, commentsPath, first.ID)
+	if status != http.StatusOK || second.ParentID == nil || *second.ParentID != top {
+		t.Fatalf("reply to a reply: got status %d and parent %s, want 200 and %d", status, parentOf(second), top)
+	}
+
+	comment, err := db.GetCommentByID(strconv.Itoa(top), strconv.Itoa(owner.ID))
+	if err != nil {
+		t.Fatalf("reading comment: %v", err)
+	}
+	if comment.NumReplies != 2 {
+		t.Errorf("top-level comment: got %d replies, want 2", comment.NumReplies)
+	}
+	replies, err := db.GetRepliesByCommentID(strconv.Itoa(first.ID), strconv.Itoa(owner.ID), 0, 10)
+	if err != nil {
+		t.Fatalf("reading replies: %v", err)
+	}
+	if len(replies) != 0 {
+		t.Errorf("reply: got %d replies to it, want 0", len(replies))
+	}
+
+	// Un commento principale eliminato resta come segnaposto: non si può rispondergli direttamente, ma rispondere a una
+	// sua risposta aggiunge ancora la risposta alla sua discussione
+	err = db.DeleteComment(strconv.Itoa(top), "20260101130000")
+	if err != nil {
+		t.Fatalf("deleting comment: %v", err)
+	}
+	if status, _ := reply(t, owner, commentsPath, top); status != http.StatusNotFound {
+		t.Errorf("reply to a tombstone: got status %d, want 404", status)
+	}
+	status, third := reply(t, owner, commentsPath, second.ID)
+	if status != http.StatusOK || third.ParentID == nil || *third.ParentID != top {
+		t.Errorf("reply to a reply of a tombstone: got status %d and parent %s, want 200 and %d", status,
+			parentOf(third), top)
+	}
+
+	// Il commento deve appartenere alla foto del percorso
+	otherPath := "/users/" + strconv.Itoa(owner.ID) + "/photos/" + newPhoto(t) + "/comments/"
+	if status, _ := reply(t, owner, otherPath, first.ID); status != http.StatusNotFound {
+		t.Errorf("reply through another photo: got status %d, want 404", status)
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// repostRequest è il corpo, facoltativo, di repostPhoto
+type repostRequest struct {
+	Comment string `json:"comment"`
+}
+
+// repostPhoto ricondivide ai follower dell'utente autenticato la foto di un altro utente, con un commento facoltativo.
+// Nello stream dei follower la foto resta attribuita al suo autore. Ricondividere di nuovo la foto ne cambia il
+// commento.
+func (rt *_router) repostPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if !checkPhotoAccess(w, ctx, user, strconv.Itoa(photo.UserID), photoID) {
+		return
+	}
+
+	if photo.UserID == user.ID {
+		http.Error(w, "Bad Request: you cannot repost your own photo", http.StatusBadRequest)
+		return
+	}
+
+	var request repostRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = ctx.Database.SetRepost(strconv.Itoa(user.ID), photoID, request.Comment, globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		log.Printf("Error saving repost: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	repost, err := ctx.Database.GetRepost(strconv.Itoa(user.ID), photoID)
+	if err != nil {
+		log.Printf("Error retrieving repost: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(repost)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// unrepostPhoto annulla la ricondivisione di una foto da parte dell'utente autenticato
+func (rt *_router) unrepostPhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.DeleteRepost(strconv.Itoa(user.ID), ps.ByName("photosId"))
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "Repost not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting repost: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
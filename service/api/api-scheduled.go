@@ -0,0 +1,135 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// errInvalidPublishAt è restituito da createPhoto quando il momento di pubblicazione richiesto non è valido
+var errInvalidPublishAt = errors.New("publish_at must be a future RFC 3339 date-time")
+
+// rescheduleRequest è il corpo di reschedulePhoto
+type rescheduleRequest struct {
+	PublishAt string `json:"publish_at"`
+}
+
+// parsePublishAt legge il momento di pubblicazione di una foto programmata, in formato RFC 3339, e lo restituisce nel
+// formato dei timestamp salvati nel database. Il momento deve essere futuro rispetto a globaltime.Now().
+func parsePublishAt(value string) (string, error) {
+	publishAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", errInvalidPublishAt
+	}
+
+	now := globaltime.Now()
+	if !publishAt.After(now) {
+		return "", errInvalidPublishAt
+	}
+
+	// I timestamp nel database sono confrontati come stringhe, quindi vanno espressi nello stesso fuso orario di now
+	return publishAt.In(now.Location()).Format(timestampFormat), nil
+}
+
+// publishScheduled pubblica le foto programmate il cui momento di pubblicazione è passato
+func (rt *_router) publishScheduled() error {
+	published, err := rt.db.PublishDuePhotos(globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		return err
+	}
+
+	if published > 0 {
+		rt.baseLogger.WithField("photos", published).Debug("scheduled photos published")
+	}
+
+	return nil
+}
+
+// getScheduledPhotos restituisce le foto programmate e non ancora pubblicate dell'utente autenticato, dalla prossima
+// da pubblicare
+func (rt *_router) getScheduledPhotos(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	photos, err := ctx.Database.GetScheduledPhotosByUserID(ps.ByName("userId"))
+	if err != nil {
+		log.Printf("Error retrieving scheduled photos: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if photos == nil {
+		photos = []database.Photo{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(photos)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// reschedulePhoto sposta la pubblicazione di una foto programmata dell'utente autenticato
+func (rt *_router) reschedulePhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || (err == nil && (photo.UserID != user.ID || photo.DeletedAt != nil)) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if photo.PublishAt == nil {
+		http.Error(w, "Conflict: photo already published", http.StatusConflict)
+		return
+	}
+
+	var request rescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	publishAt, err := parsePublishAt(request.PublishAt)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// La foto potrebbe essere stata pubblicata dal job dopo averla letta
+	err = ctx.Database.ReschedulePhoto(photoID, publishAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Conflict: photo already published", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error rescheduling photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	photo.Timestamp = publishAt
+	photo.PublishAt = &publishAt
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(photo)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
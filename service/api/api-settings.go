@@ -0,0 +1,205 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// accountSettings sono le impostazioni dell'account
+type accountSettings struct {
+	DefaultVisibility string `json:"default_visibility"`
+	Private           bool   `json:"private"`
+}
+
+// accountSettingsRequest è il corpo di updateAccountSettings: i campi omessi non vengono modificati
+type accountSettingsRequest struct {
+	DefaultVisibility *string `json:"default_visibility"`
+	Private           *bool   `json:"private"`
+}
+
+// photoSettings sono le impostazioni di una foto
+type photoSettings struct {
+	Visibility    string `json:"visibility"`
+	CommentPolicy string `json:"comment_policy"`
+}
+
+// photoSettingsRequest è il corpo di updatePhotoSettings: i campi omessi non vengono modificati
+type photoSettingsRequest struct {
+	Visibility    *string `json:"visibility"`
+	CommentPolicy *string `json:"comment_policy"`
+}
+
+// authenticateOwner autentica l'utente della richiesta e verifica che sia l'utente userId del percorso. In caso di
+// errore scrive la risposta e restituisce false.
+func authenticateOwner(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) (database.User, bool) {
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return database.User{}, false
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, false
+	}
+
+	if strconv.Itoa(user.ID) != ps.ByName("userId") {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return user, false
+	}
+
+	return user, true
+}
+
+// writeAccountSettings risponde con le impostazioni attuali dell'account userID
+func writeAccountSettings(w http.ResponseWriter, ctx reqcontext.RequestContext, userID string) {
+	defaultVisibility, err := ctx.Database.GetDefaultVisibility(userID)
+	if err != nil {
+		log.Printf("Error retrieving account settings: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	private, err := ctx.Database.IsPrivate(userID)
+	if err != nil {
+		log.Printf("Error retrieving account settings: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(accountSettings{
+		DefaultVisibility: defaultVisibility,
+		Private:           private,
+	})
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// getAccountSettings restituisce le impostazioni dell'account dell'utente autenticato
+func (rt *_router) getAccountSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	writeAccountSettings(w, ctx, ps.ByName("userId"))
+}
+
+// updateAccountSettings modifica le impostazioni dell'account dell'utente autenticato. La visibilità predefinita vale
+// per le foto pubblicate da quel momento in poi; rendendo pubblico un account privato si approvano le richieste di
+// follow in attesa.
+func (rt *_router) updateAccountSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	userID := ps.ByName("userId")
+
+	var request accountSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.DefaultVisibility != nil {
+		if !database.IsValidVisibility(*request.DefaultVisibility) {
+			http.Error(w, "Bad Request: "+errInvalidVisibility.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err := ctx.Database.SetDefaultVisibility(userID, *request.DefaultVisibility)
+		if err != nil {
+			log.Printf("Error updating default visibility: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if request.Private != nil {
+		err := ctx.Database.SetPrivate(userID, *request.Private)
+		if err != nil {
+			log.Printf("Error updating private account: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeAccountSettings(w, ctx, userID)
+}
+
+// updatePhotoSettings modifica le impostazioni di una foto dell'utente autenticato
+func (rt *_router) updatePhotoSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photoID := ps.ByName("photosId")
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || (err == nil && photo.UserID != user.ID) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var request photoSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	settings := photoSettings{
+		Visibility:    photo.Visibility,
+		CommentPolicy: photo.CommentPolicy,
+	}
+
+	// Entrambi i valori vengono verificati prima di salvare, così una richiesta non valida non cambia nulla
+	if request.Visibility != nil && !database.IsValidVisibility(*request.Visibility) {
+		http.Error(w, "Bad Request: "+errInvalidVisibility.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.CommentPolicy != nil && !database.IsValidCommentPolicy(*request.CommentPolicy) {
+		http.Error(w, "Bad Request: "+errInvalidCommentPolicy.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Visibility != nil {
+		err = ctx.Database.SetPhotoVisibility(photoID, *request.Visibility)
+		if err != nil {
+			log.Printf("Error updating photo visibility: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		settings.Visibility = *request.Visibility
+	}
+
+	if request.CommentPolicy != nil {
+		err = ctx.Database.SetPhotoCommentPolicy(photoID, *request.CommentPolicy)
+		if err != nil {
+			log.Printf("Error updating comment policy: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		settings.CommentPolicy = *request.CommentPolicy
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(settings)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
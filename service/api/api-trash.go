@@ -0,0 +1,191 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// trashedPhoto è una foto nel cestino; PurgeAt è il momento in cui verrà eliminata definitivamente
+type trashedPhoto struct {
+	database.Photo
+	PurgeAt string `json:"purge_at"`
+}
+
+// purgeAt restituisce il momento (formato YYYYMMDDHHmmSS) in cui la foto nel cestino verrà eliminata definitivamente
+func (rt *_router) purgeAt(photo database.Photo) string {
+	deletedAt, err := time.ParseInLocation(timestampFormat, *photo.DeletedAt, globaltime.Now().Location())
+	if err != nil {
+		return ""
+	}
+	return deletedAt.Add(rt.trashRetention).Format(timestampFormat)
+}
+
+// purgeTrash elimina definitivamente le foto rimaste nel cestino più a lungo di rt.trashRetention
+func (rt *_router) purgeTrash() error {
+	photoIDs, err := rt.db.GetPhotosDeletedBefore(globaltime.Now().Add(-rt.trashRetention).Format(timestampFormat))
+	if err != nil {
+		return err
+	}
+
+	for _, photoID := range photoIDs {
+		err = rt.db.DeletePhoto(strconv.Itoa(photoID))
+		if err != nil {
+			return err
+		}
+		rt.baseLogger.WithField("photo", photoID).Debug("trashed photo purged")
+	}
+
+	return nil
+}
+
+// loadTrashedPhoto restituisce la foto photosId del percorso se è nel cestino dell'utente; altrimenti risponde con 404
+// e restituisce false
+func loadTrashedPhoto(w http.ResponseWriter, ps httprouter.Params, ctx reqcontext.RequestContext, user database.User) (database.Photo, bool) {
+	photoID := ps.ByName("photosId")
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || (err == nil && (photo.UserID != user.ID || photo.DeletedAt == nil)) {
+		http.Error(w, "Photo not found in trash", http.StatusNotFound)
+		return photo, false
+	} else if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return photo, false
+	}
+
+	return photo, true
+}
+
+// getTrash restituisce le foto nel cestino dell'utente autenticato, dall'ultima eliminata
+func (rt *_router) getTrash(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	photos, err := ctx.Database.GetTrashedPhotosByUserID(ps.ByName("userId"))
+	if err != nil {
+		log.Printf("Error retrieving trash: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	trash := make([]trashedPhoto, 0, len(photos))
+	for _, photo := range photos {
+		trash = append(trash, trashedPhoto{Photo: photo, PurgeAt: rt.purgeAt(photo)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(trash)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// restorePhoto riporta una foto dal cestino al profilo dell'utente autenticato, con likes e commenti, se rientra ancora
+// nella sua quota
+func (rt *_router) restorePhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photo, ok := loadTrashedPhoto(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+
+	// Le foto nel cestino non occupano quota: la foto ripristinata deve rientrarci come un nuovo post
+	size, err := ctx.Database.GetPhotoSize(strconv.Itoa(photo.ID))
+	if err != nil {
+		log.Printf("Error retrieving size of photo %d: %v", photo.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	lock := rt.quotaLock(user.ID)
+	lock.Lock()
+	defer lock.Unlock()
+	err = rt.checkQuota(ctx.Database, user.ID, size)
+	if errors.Is(err, errQuotaExceeded) {
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Printf("Error checking quota: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// La foto potrebbe essere stata eliminata definitivamente dopo averla letta
+	err = ctx.Database.RestorePhoto(strconv.Itoa(photo.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Photo not found in trash", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error restoring photo %d: %v", photo.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	photo.DeletedAt = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(photo)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// purgePhoto elimina definitivamente una foto dal cestino dell'utente autenticato, con likes e commenti
+func (rt *_router) purgePhoto(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	user, ok := authenticateOwner(w, r, ps, ctx)
+	if !ok {
+		return
+	}
+
+	photo, ok := loadTrashedPhoto(w, ps, ctx, user)
+	if !ok {
+		return
+	}
+
+	err := ctx.Database.DeletePhoto(strconv.Itoa(photo.ID))
+	if err != nil {
+		log.Printf("Error purging photo %d: %v", photo.ID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// emptyTrash elimina definitivamente tutte le foto nel cestino dell'utente autenticato
+func (rt *_router) emptyTrash(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+	if _, ok := authenticateOwner(w, r, ps, ctx); !ok {
+		return
+	}
+
+	photos, err := ctx.Database.GetTrashedPhotosByUserID(ps.ByName("userId"))
+	if err != nil {
+		log.Printf("Error retrieving trash: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, photo := range photos {
+		err = ctx.Database.DeletePhoto(strconv.Itoa(photo.ID))
+		if err != nil {
+			log.Printf("Error purging photo %d: %v", photo.ID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
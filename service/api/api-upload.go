@@ -0,0 +1,490 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Gli upload ripristinabili seguono il protocollo tus 1.0.0 (https://tus.io/protocols/resumable-upload), con le
+// estensioni creation, expiration e termination: il client crea l'upload con POST indicando la dimensione totale,
+// invia i dati a blocchi con PATCH dichiarando l'offset di ciascun blocco e, dopo un'interruzione, chiede con HEAD
+// quanti byte sono arrivati per riprendere da lì. I dati vengono scritti in un file temporaneo; quando l'upload è
+// completo il file diventa un post con la stessa pipeline di uploadPhoto.
+
+// tusVersion è la versione del protocollo tus supportata
+const tusVersion = "1.0.0"
+
+// uploadContentType è il Content-Type richiesto per i blocchi inviati con PATCH
+const uploadContentType = "application/offset+octet-stream"
+
+// checkTusVersion controlla l'header Tus-Resumable, se presente, e imposta quello della risposta. In caso di versione
+// non supportata scrive la risposta e restituisce false.
+func checkTusVersion(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if version := r.Header.Get("Tus-Resumable"); version != "" && version != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		http.Error(w, "Precondition Failed: unsupported tus version", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// parseUploadMetadata decodifica l'header Upload-Metadata: coppie "chiave valore-base64" separate da virgole
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, " ", 2)
+		value := ""
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("decoding metadata %s: %w", parts[0], err)
+			}
+			value = string(decoded)
+		}
+		metadata[parts[0]] = value
+	}
+	return metadata, nil
+}
+
+// uploadPath restituisce il percorso del file temporaneo dell'upload
+func (rt *_router) uploadPath(uploadID string) string {
+	return filepath.Join(rt.uploadsDirectory, uploadID)
+}
+
+// uploadLock restituisce il mutex che serializza le scritture sull'upload
+func (rt *_router) uploadLock(uploadID string) *sync.Mutex {
+	lock, _ := rt.uploadLocks.LoadOrStore(uploadID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// removeUpload elimina l'upload dal database insieme al suo file temporaneo
+func (rt *_router) removeUpload(db database.AppDatabase, uploadID string) error {
+	err := os.Remove(rt.uploadPath(uploadID))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing upload file: %w", err)
+	}
+
+	err = db.DeleteUpload(uploadID)
+	if err != nil {
+		return err
+	}
+
+	rt.uploadLocks.Delete(uploadID)
+	return nil
+}
+
+// expireUploads elimina gli upload abbandonati, cioè non più aggiornati entro la loro scadenza
+func (rt *_router) expireUploads() error {
+	uploads, err := rt.db.GetExpiredUploads(globaltime.Now().Format(timestampFormat))
+	if err != nil {
+		return err
+	}
+
+	for _, upload := range uploads {
+		removed, err := rt.expireUpload(upload.ID)
+		if err != nil {
+			return err
+		}
+		if removed {
+			rt.baseLogger.WithField("upload", upload.ID).Debug("expired upload removed")
+		}
+	}
+
+	return nil
+}
+
+// expireUpload elimina l'upload uploadID se è ancora scaduto. Tiene il lock dell'upload, così da non eliminarlo mentre
+// una PATCH sta scrivendo, e ricontrolla la scadenza perché la PATCH può averla rinnovata nel frattempo.
+func (rt *_router) expireUpload(uploadID string) (bool, error) {
+	lock := rt.uploadLock(uploadID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	upload, err := rt.db.GetUploadByID(uploadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if upload.ExpiresAt >= globaltime.Now().Format(timestampFormat) {
+		return false, nil
+	}
+
+	return true, rt.removeUpload(rt.db, uploadID)
+}
+
+// setUploadHeaders imposta gli header che descrivono lo stato dell'upload
+func setUploadHeaders(w http.ResponseWriter, upload database.Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if expiresAt, err := time.ParseInLocation(timestampFormat, upload.ExpiresAt, time.Local); err == nil {
+		w.Header().Set("Upload-Expires", expiresAt.UTC().Format(http.TimeFormat))
+	}
+	if upload.PhotoID != nil {
+		w.Header().Set("Photo-Id", strconv.Itoa(*upload.PhotoID))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// loadUserUpload legge l'upload uploadID di user; gli upload scaduti vengono eliminati, quindi va chiamata tenendo
+// uploadLock(uploadID). In caso di errore scrive la risposta e restituisce false.
+func (rt *_router) loadUserUpload(w http.ResponseWriter, ctx reqcontext.RequestContext, user database.User, uploadID string) (database.Upload, bool) {
+	upload, err := ctx.Database.GetUploadByID(uploadID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && upload.UserID != user.ID) {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return upload, false
+	} else if err != nil {
+		log.Printf("Error retrieving upload %s: %v", uploadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return upload, false
+	}
+
+	if upload.ExpiresAt < globaltime.Now().Format(timestampFormat) {
+		if err := rt.removeUpload(ctx.Database, uploadID); err != nil {
+			log.Printf("Error removing expired upload %s: %v", uploadID, err)
+		}
+		http.Error(w, "Upload expired", http.StatusGone)
+		return upload, false
+	}
+
+	return upload, true
+}
+
+// createUpload crea un nuovo upload ripristinabile. La dimensione totale è nell'header Upload-Length, didascalia e
+// visibilità possono essere indicate nei metadati (Upload-Metadata) con le chiavi "caption" e "visibility", il momento
+// di pubblicazione di un post programmato con la chiave "publish_at".
+func (rt *_router) createUpload(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID := ps.ByName("userId")
+	if strconv.Itoa(user.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		http.Error(w, "Bad Request: invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > rt.uploadsMaxSize {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(rt.uploadsMaxSize, 10))
+		http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Un upload che non rientrerebbe nella quota viene rifiutato subito, prima di ricevere i dati
+	err = rt.checkQuota(ctx.Database, user.ID, length)
+	if err != nil {
+		writeCreatePhotoError(w, err)
+		return
+	}
+
+	metadata := r.Header.Get("Upload-Metadata")
+	parsedMetadata, err := parseUploadMetadata(metadata)
+	if err != nil {
+		http.Error(w, "Bad Request: invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	if visibility := parsedMetadata["visibility"]; visibility != "" && !database.IsValidVisibility(visibility) {
+		writeCreatePhotoError(w, errInvalidVisibility)
+		return
+	}
+	if publishAt := parsedMetadata["publish_at"]; publishAt != "" {
+		if _, err := parsePublishAt(publishAt); err != nil {
+			writeCreatePhotoError(w, err)
+			return
+		}
+	}
+
+	uploadUUID, err := uuid.NewV4()
+	if err != nil {
+		log.Printf("Error generating upload ID: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	upload := database.Upload{
+		ID:        uploadUUID.String(),
+		UserID:    user.ID,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: globaltime.Now().Add(rt.uploadsExpiry).Format(timestampFormat),
+	}
+
+	// Il file temporaneo viene creato subito, i blocchi verranno aggiunti in coda
+	file, err := os.OpenFile(rt.uploadPath(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Printf("Error creating upload file: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	_ = file.Close()
+
+	err = ctx.Database.SetUpload(upload)
+	if err != nil {
+		_ = os.Remove(rt.uploadPath(upload.ID))
+		log.Printf("Error saving upload: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Location", "/users/"+userID+"/uploads/"+upload.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// getUploadStatus restituisce negli header lo stato dell'upload: l'offset da cui riprendere e, se l'upload è
+// completo, l'ID della foto creata (Photo-Id)
+func (rt *_router) getUploadStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// loadUserUpload può eliminare l'upload se è scaduto: come per le scritture serve il lock
+	uploadID := ps.ByName("uploadId")
+	lock := rt.uploadLock(uploadID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	upload, ok := rt.loadUserUpload(w, ctx, user, uploadID)
+	if !ok {
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusOK)
+}
+
+// patchUpload aggiunge un blocco di dati all'upload. L'header Upload-Offset deve corrispondere ai byte già ricevuti;
+// se la connessione cade a metà i byte arrivati vengono comunque salvati e il client può riprendere dopo una HEAD.
+// Con l'ultimo blocco l'upload viene trasformato in un post; se l'utente ha già foto quasi identiche i loro ID sono
+// nell'header Duplicate-Of.
+func (rt *_router) patchUpload(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != uploadContentType {
+		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Bad Request: invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	uploadID := ps.ByName("uploadId")
+	lock := rt.uploadLock(uploadID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	upload, ok := rt.loadUserUpload(w, ctx, user, uploadID)
+	if !ok {
+		return
+	}
+
+	if upload.PhotoID != nil {
+		// L'upload è già diventato un post
+		setUploadHeaders(w, upload)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if offset != upload.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		http.Error(w, "Conflict: Upload-Offset does not match", http.StatusConflict)
+		return
+	}
+
+	file, err := os.OpenFile(rt.uploadPath(uploadID), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Printf("Error opening upload file %s: %v", uploadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// I byte oltre la dimensione dichiarata vengono ignorati
+	written, copyErr := io.Copy(file, io.LimitReader(r.Body, upload.Length-upload.Offset))
+	closeErr := file.Close()
+	if closeErr != nil && copyErr == nil {
+		copyErr = closeErr
+	}
+
+	upload.Offset += written
+	upload.ExpiresAt = globaltime.Now().Add(rt.uploadsExpiry).Format(timestampFormat)
+	err = ctx.Database.UpdateUploadOffset(uploadID, upload.Offset, upload.ExpiresAt)
+	if err != nil {
+		log.Printf("Error updating upload %s: %v", uploadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if copyErr != nil {
+		// Connessione interrotta: i byte ricevuti restano salvati
+		log.Printf("Upload %s interrupted at offset %d: %v", uploadID, upload.Offset, copyErr)
+		setUploadHeaders(w, upload)
+		http.Error(w, "Upload interrupted", http.StatusBadRequest)
+		return
+	}
+
+	if upload.Offset == upload.Length {
+		photo, err := rt.finalizeUpload(ctx, user, upload)
+		if err != nil {
+			writeCreatePhotoError(w, err)
+			return
+		}
+		upload.PhotoID = &photo.ID
+		if len(photo.DuplicateOf) > 0 {
+			w.Header().Set("Duplicate-Of", joinIDs(photo.DuplicateOf))
+		}
+	}
+
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finalizeUpload trasforma un upload completo in un post, usando la stessa pipeline di uploadPhoto, ed elimina il file
+// temporaneo. L'upload resta nel database fino alla scadenza, così una HEAD può ancora restituire la foto creata.
+func (rt *_router) finalizeUpload(ctx reqcontext.RequestContext, user database.User, upload database.Upload) (createdPhoto, error) {
+	imageData, err := ioutil.ReadFile(rt.uploadPath(upload.ID))
+	if err != nil {
+		return createdPhoto{}, fmt.Errorf("reading upload file: %w", err)
+	}
+
+	metadata, err := parseUploadMetadata(upload.Metadata)
+	if err != nil {
+		return createdPhoto{}, err
+	}
+
+	photo, err := rt.createPhoto(ctx, user, newPhoto{
+		Images:     [][]byte{imageData},
+		Caption:    metadata["caption"],
+		Visibility: metadata["visibility"],
+		PublishAt:  metadata["publish_at"],
+	})
+	if err != nil {
+		return photo, err
+	}
+
+	err = ctx.Database.SetUploadPhoto(upload.ID, int64(photo.ID))
+	if err != nil {
+		return photo, err
+	}
+
+	err = os.Remove(rt.uploadPath(upload.ID))
+	if err != nil {
+		log.Printf("Error removing upload file %s: %v", upload.ID, err)
+	}
+
+	return photo, nil
+}
+
+// deleteUpload annulla un upload ripristinabile ed elimina i dati già ricevuti
+func (rt *_router) deleteUpload(w http.ResponseWriter, r *http.Request, ps httprouter.Params, ctx reqcontext.RequestContext) {
+
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	token, err := reqcontext.ExtractBearerToken(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Autentica l'utente utilizzando il token
+	user, err := reqcontext.AuthenticateUser(token, ctx.Database)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	uploadID := ps.ByName("uploadId")
+	lock := rt.uploadLock(uploadID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, ok := rt.loadUserUpload(w, ctx, user, uploadID); !ok {
+		return
+	}
+
+	err = rt.removeUpload(ctx.Database, uploadID)
+	if err != nil {
+		log.Printf("Error deleting upload %s: %v", uploadID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,13 +1,16 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
 	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
 	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/globaltime"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -99,8 +102,6 @@ func (rt *_router) getUserProfile(w http.ResponseWriter, r *http.Request, ps htt
 	userId := ps.ByName("userId")
 	log.Printf("Getting profile for user ID: %s", userId)
 
-	var loggedUser = ctx.User
-
 	token, err := reqcontext.ExtractBearerToken(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -108,7 +109,7 @@ func (rt *_router) getUserProfile(w http.ResponseWriter, r *http.Request, ps htt
 	}
 
 	// Autentica l'utente utilizzando il token
-	_, err = reqcontext.AuthenticateUser(token, ctx.Database)
+	loggedUser, err := reqcontext.AuthenticateUser(token, ctx.Database)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -132,74 +133,100 @@ func (rt *_router) getUserProfile(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	followers, err := ctx.Database.GetFollowers(userId)
+	numFollowers, err := ctx.Database.CountFollowersByUserID(userId)
 	if err != nil {
-		log.Printf("Error retrieving followers: %v", err)
+		log.Printf("Error counting followers: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	numFollowers, err := ctx.Database.CountFollowersByUserID(userId)
+	numFollows, err := ctx.Database.CountFollowsByUserID(userId)
 	if err != nil {
-		log.Printf("Error counting followers: %v", err)
+		log.Printf("Error counting follows: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	follows, err := ctx.Database.GetFollows(userId)
+	photos, err := ctx.Database.GetPhotosByUserID(userId, strconv.Itoa(loggedUser.ID))
 	if err != nil {
-		log.Printf("Error retrieving follows: %v", err)
+		log.Printf("Error retrieving photos: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	numFollows, err := ctx.Database.CountFollowsByUserID(userId)
+	numPhotos, err := ctx.Database.CountPhotosByUserID(userId, strconv.Itoa(loggedUser.ID))
 	if err != nil {
-		log.Printf("Error counting follows: %v", err)
+		log.Printf("Error counting photos: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	photos, err := ctx.Database.GetPhotosByUserID(userId)
+	bans, err := ctx.Database.GetBans(userId)
 	if err != nil {
-		log.Printf("Error retrieving photos: %v", err)
+		log.Printf("Error retrieving bans: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	numPhotos, err := ctx.Database.CountPhotosByUserID(userId)
+	albums, err := ctx.Database.GetAlbumsByUserID(userId, strconv.Itoa(loggedUser.ID))
 	if err != nil {
-		log.Printf("Error counting photos: %v", err)
+		log.Printf("Error retrieving albums: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	bans, err := ctx.Database.GetBans(userId)
+	// Le foto di un account privato sono visibili solo ai follower approvati (vedi GetPhotosByUserID); chi ha una
+	// richiesta in attesa lo vede da followRequested
+	private, err := ctx.Database.IsPrivate(userId)
 	if err != nil {
-		log.Printf("Error retrieving bans: %v", err)
+		log.Printf("Error checking private account: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	followRequested, err := ctx.Database.HasFollowRequest(strconv.Itoa(loggedUser.ID), userId)
+	if err != nil {
+		log.Printf("Error checking follow request: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// Costruisci il profilo utente con tutte le informazioni
+	// La quota è visibile solo nel proprio profilo
+	var quota *quotaStatus
+	if loggedUser.ID == user.ID {
+		status, err := rt.getQuota(ctx.Database, userId)
+		if err != nil {
+			log.Printf("Error retrieving quota: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		quota = &status
+	}
+
+	// Costruisci il profilo utente con tutte le informazioni; le liste dei follower e dei seguiti si leggono a pagine
+	// con getFollowers e getFollowing
 	userProfile := struct {
-		User         database.User    `json:"user"`
-		Followers    []database.User  `json:"followers"`
-		NumFollowers int              `json:"numFollowers"`
-		Follows      []database.User  `json:"follows"`
-		NumFollowing int              `json:"numFollowing"`
-		Photos       []database.Photo `json:"Photos"`
-		NumPhotos    int              `json:"numPhotos"`
-		Bans         []database.User  `json:"bans"`
+		User            database.User    `json:"user"`
+		NumFollowers    int              `json:"numFollowers"`
+		NumFollowing    int              `json:"numFollowing"`
+		Photos          []database.Photo `json:"Photos"`
+		NumPhotos       int              `json:"numPhotos"`
+		Bans            []database.User  `json:"bans"`
+		Albums          []database.Album `json:"albums"`
+		Quota           *quotaStatus     `json:"quota,omitempty"`
+		Private         bool             `json:"private"`
+		FollowRequested bool             `json:"follow_requested"`
 	}{
-		User:         user,
-		Followers:    followers,
-		NumFollowers: numFollowers,
-		Follows:      follows,
-		NumFollowing: numFollows,
-		Photos:       photos,
-		NumPhotos:    numPhotos,
-		Bans:         bans,
+		User:            user,
+		NumFollowers:    numFollowers,
+		NumFollowing:    numFollows,
+		Photos:          photos,
+		NumPhotos:       numPhotos,
+		Bans:            bans,
+		Albums:          albums,
+		Quota:           quota,
+		Private:         private,
+		FollowRequested: followRequested,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -233,16 +260,18 @@ func (rt *_router) getMyStream(w http.ResponseWriter, r *http.Request, ps httpro
 		return
 	}
 
-	// Costruisci una struttura temporanea con le informazioni di likes e comments
+	// Costruisci una struttura temporanea con le informazioni di likes, comments, reposts e reazioni
 	var userStream struct {
 		Photos []struct {
 			database.Photo
-			Likes    int `json:"likes"`
-			Comments int `json:"comments"`
+			Likes     int            `json:"likes"`
+			Comments  int            `json:"comments"`
+			Reposts   int            `json:"reposts"`
+			Reactions map[string]int `json:"reactions"`
 		} `json:"Photos"`
 	}
 
-	// Itera su ogni foto per aggiungere le informazioni di likes e comments
+	// Itera su ogni foto per aggiungere le informazioni di likes, comments, reposts e reazioni
 	for _, photo := range photos {
 		photoID := strconv.Itoa(photo.ID)
 
@@ -258,15 +287,31 @@ func (rt *_router) getMyStream(w http.ResponseWriter, r *http.Request, ps httpro
 			return
 		}
 
-		// Aggiungi la foto con le informazioni di likes e comments alla struttura temporanea
+		reposts, err := ctx.Database.CountRepostsByPhotoID(photoID)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		reactions, err := ctx.Database.CountReactionsByPhotoID(photoID)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		// Aggiungi la foto con le informazioni di likes, comments, reposts e reazioni alla struttura temporanea
 		userStream.Photos = append(userStream.Photos, struct {
 			database.Photo
-			Likes    int `json:"likes"`
-			Comments int `json:"comments"`
+			Likes     int            `json:"likes"`
+			Comments  int            `json:"comments"`
+			Reposts   int            `json:"reposts"`
+			Reactions map[string]int `json:"reactions"`
 		}{
-			Photo:    photo,
-			Likes:    likes,
-			Comments: comments,
+			Photo:     photo,
+			Likes:     likes,
+			Comments:  comments,
+			Reposts:   reposts,
+			Reactions: reactions,
 		})
 	}
 
@@ -307,6 +352,42 @@ func (rt *_router) followUser(w http.ResponseWriter, r *http.Request, ps httprou
 	}
 
 	followedID := ps.ByName("followedId")
+
+	// Per seguire un account privato serve l'approvazione: viene registrata una richiesta di follow
+	private, err := ctx.Database.IsPrivate(followedID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error checking private account: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if private && strconv.Itoa(user.ID) != followedID {
+		isFollowed, err := ctx.Database.IsFollowed(strconv.Itoa(user.ID), followedID)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if !isFollowed {
+			err = ctx.Database.SetFollowRequest(strconv.Itoa(user.ID), followedID, globaltime.Now().Format(timestampFormat))
+			if err != nil {
+				log.Printf("Error saving follow request: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(struct {
+				Status string `json:"status"`
+			}{Status: "requested"})
+			return
+		}
+	}
+
 	log.Printf("Before calling FollowUser: userID = %d, followedID = %s", user.ID, followedID)
 	err = ctx.Database.FollowUser(strconv.Itoa(user.ID), followedID)
 	if err != nil {
@@ -391,6 +472,20 @@ func (rt *_router) banUser(w http.ResponseWriter, r *http.Request, ps httprouter
 		return
 	}
 
+	// rimuove le richieste di follow in attesa, in entrambi i versi
+
+	err = ctx.Database.DeleteFollowRequest(strconv.Itoa(user.ID), ps.ByName("bannedId"))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err = ctx.Database.DeleteFollowRequest(ps.ByName("bannedId"), strconv.Itoa(user.ID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
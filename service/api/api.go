@@ -9,14 +9,27 @@ Example:
 
 	// Create the API router
 	apirouter, err := api.New(api.Config{
-		Logger:   logger,
-		Database: appdb,
+		Logger:                logger,
+		Database:              appdb,
+		UploadsDirectory:      cfg.Uploads.Directory,
+		UploadsExpiry:         cfg.Uploads.Expiry,
+		UploadsMaxSize:        cfg.Uploads.MaxSize,
+		JobsInterval:          cfg.Jobs.Interval,
+		QuotaMaxPhotos:        cfg.Quota.MaxPhotos,
+		QuotaMaxBytes:         cfg.Quota.MaxBytes,
+		AdminUsernames:        cfg.Admin.Usernames,
+		TrashRetention:        cfg.Trash.Retention,
+		ViewsWindow:           cfg.Views.Window,
+		DuplicatesMode:        cfg.Duplicates.Mode,
+		DuplicatesMaxDistance: cfg.Duplicates.MaxDistance,
+		ReactionTypes:         cfg.Reactions.Types,
 	})
 	if err != nil {
 		logger.WithError(err).Error("error creating the API server instance")
 		return fmt.Errorf("error creating the API server instance: %w", err)
 	}
 	router := apirouter.Handler()
+	apirouter.StartJobs()
 
 	// ... other stuff here, like middleware chaining, etc.
 
@@ -38,12 +51,20 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
+// timestampFormat è il formato dei timestamp salvati nel database: YYYYMMDDHHmmSS
+const timestampFormat = "20060102150405"
+
 // Config is used to provide dependencies and configuration to the New function.
 type Config struct {
 	// Logger where log entries are sent
@@ -51,6 +72,45 @@ type Config struct {
 
 	// Database is the instance of database.AppDatabase where data are saved
 	Database database.AppDatabase
+
+	// UploadsDirectory is the directory where the data of resumable uploads is stored until the upload is complete.
+	// The default is a subdirectory of the system temporary directory
+	UploadsDirectory string
+
+	// UploadsExpiry is how long an abandoned resumable upload is kept before being deleted
+	UploadsExpiry time.Duration
+
+	// UploadsMaxSize is the maximum size in bytes of a resumable upload
+	UploadsMaxSize int64
+
+	// JobsInterval is how often background jobs run
+	JobsInterval time.Duration
+
+	// QuotaMaxPhotos and QuotaMaxBytes are the default limits on the photos a user can store (number of posts and
+	// total size of their images). Zero means no limit. Administrators can override them for single users
+	QuotaMaxPhotos int
+	QuotaMaxBytes  int64
+
+	// AdminUsernames are the usernames of the administrators
+	AdminUsernames []string
+
+	// TrashRetention is how long a deleted photo is kept in the trash of its owner before being deleted permanently
+	TrashRetention time.Duration
+
+	// ViewsWindow is the time window in which repeated views of a photo by the same user are counted once
+	ViewsWindow time.Duration
+
+	// DuplicatesMode is what happens when a user posts a photo nearly identical to one of their photos: "warn" publishes
+	// it and reports the similar photos, "reject" refuses it
+	DuplicatesMode string
+
+	// DuplicatesMaxDistance is the maximum number of different bits between the perceptual hashes of two images
+	// considered nearly identical. It also applies to the hashes blocked by the administrators
+	DuplicatesMaxDistance int
+
+	// ReactionTypes are the reactions users can leave on photos and comments. They must include
+	// database.DefaultReaction, the reaction of the likes APIs
+	ReactionTypes []string
 }
 
 // Router is the package API interface representing an API handler builder
@@ -58,6 +118,9 @@ type Router interface {
 	// Handler returns an HTTP handler for APIs provided in this package
 	Handler() http.Handler
 
+	// StartJobs starts the background jobs of the package in a separate goroutine. Jobs are stopped by Close
+	StartJobs()
+
 	// Close terminates any resource used in the package
 	Close() error
 }
@@ -71,6 +134,42 @@ func New(cfg Config) (Router, error) {
 	if cfg.Database == nil {
 		return nil, errors.New("database is required")
 	}
+	if cfg.UploadsDirectory == "" {
+		cfg.UploadsDirectory = filepath.Join(os.TempDir(), "wasaphoto-uploads")
+	}
+	if cfg.UploadsExpiry <= 0 {
+		return nil, errors.New("uploads expiry must be positive")
+	}
+	if cfg.UploadsMaxSize <= 0 {
+		return nil, errors.New("uploads max size must be positive")
+	}
+	if cfg.JobsInterval <= 0 {
+		return nil, errors.New("jobs interval must be positive")
+	}
+	if cfg.QuotaMaxPhotos < 0 || cfg.QuotaMaxBytes < 0 {
+		return nil, errors.New("quota limits cannot be negative")
+	}
+	if cfg.TrashRetention <= 0 {
+		return nil, errors.New("trash retention must be positive")
+	}
+	if cfg.ViewsWindow <= 0 {
+		return nil, errors.New("views window must be positive")
+	}
+	if cfg.DuplicatesMode != duplicatesWarn && cfg.DuplicatesMode != duplicatesReject {
+		return nil, errors.New("duplicates mode must be warn or reject")
+	}
+	if cfg.DuplicatesMaxDistance < 0 || cfg.DuplicatesMaxDistance > 64 {
+		return nil, errors.New("duplicates max distance must be from 0 to 64")
+	}
+	if err := validateReactionTypes(cfg.ReactionTypes); err != nil {
+		return nil, err
+	}
+
+	// The directory for resumable uploads must exist before the first upload starts
+	err := os.MkdirAll(cfg.UploadsDirectory, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("creating uploads directory: %w", err)
+	}
 
 	// Create a new router where we will register HTTP endpoints. The server will pass requests to this router to be
 	// handled.
@@ -79,9 +178,22 @@ func New(cfg Config) (Router, error) {
 	router.RedirectFixedPath = false
 
 	return &_router{
-		router:     router,
-		baseLogger: cfg.Logger,
-		db:         cfg.Database,
+		router:                router,
+		baseLogger:            cfg.Logger,
+		db:                    cfg.Database,
+		uploadsDirectory:      cfg.UploadsDirectory,
+		uploadsExpiry:         cfg.UploadsExpiry,
+		uploadsMaxSize:        cfg.UploadsMaxSize,
+		jobsInterval:          cfg.JobsInterval,
+		quotaMaxPhotos:        cfg.QuotaMaxPhotos,
+		quotaMaxBytes:         cfg.QuotaMaxBytes,
+		adminUsernames:        cfg.AdminUsernames,
+		trashRetention:        cfg.TrashRetention,
+		viewsWindow:           cfg.ViewsWindow,
+		duplicatesMode:        cfg.DuplicatesMode,
+		duplicatesMaxDistance: cfg.DuplicatesMaxDistance,
+		reactionTypes:         cfg.ReactionTypes,
+		stopJobs:              make(chan struct{}),
 	}, nil
 }
 
@@ -93,4 +205,41 @@ type _router struct {
 	baseLogger logrus.FieldLogger
 
 	db database.AppDatabase
+
+	// uploadsDirectory, uploadsExpiry and uploadsMaxSize configure resumable uploads (see api-upload.go)
+	uploadsDirectory string
+	uploadsExpiry    time.Duration
+	uploadsMaxSize   int64
+
+	// uploadLocks holds a *sync.Mutex for each resumable upload, so that chunks of the same upload are written one at
+	// a time
+	uploadLocks sync.Map
+
+	// quotaMaxPhotos and quotaMaxBytes are the default quota (see quotas.go); quotaLocks holds a *sync.Mutex for each
+	// user, so that new posts of the same user are checked against the quota one at a time
+	quotaMaxPhotos int
+	quotaMaxBytes  int64
+	quotaLocks     sync.Map
+
+	// adminUsernames are the usernames of the administrators
+	adminUsernames []string
+
+	// trashRetention is how long deleted photos stay in the trash (see api-trash.go)
+	trashRetention time.Duration
+
+	// viewsWindow is the deduplication window of photo views (see api-insights.go)
+	viewsWindow time.Duration
+
+	// duplicatesMode and duplicatesMaxDistance configure the detection of nearly identical photos (see duplicates.go)
+	duplicatesMode        string
+	duplicatesMaxDistance int
+
+	// reactionTypes are the reactions users can leave on photos and comments (see api-reactions.go)
+	reactionTypes []string
+
+	// jobsInterval is how often background jobs run. Jobs stop when stopJobs is closed, jobsWg waits for them
+	jobsInterval time.Duration
+	stopJobs     chan struct{}
+	stopOnce     sync.Once
+	jobsWg       sync.WaitGroup
 }
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestDatabase apre un database SQLite vuoto nella directory temporanea del test
+func newTestDatabase(t *testing.T) database.AppDatabase {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	db, err := database.New(conn)
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	return db
+}
+
+// newTestUser crea l'utente name e lo restituisce
+func newTestUser(t *testing.T, db database.AppDatabase, name string) database.User {
+	t.Helper()
+
+	err := db.SetUser(name)
+	if err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	user, err := db.GetUserByUsername(name)
+	if err != nil {
+		t.Fatalf("reading user %s: %v", name, err)
+	}
+	return user
+}
+
+// newTestRouter restituisce l'handler delle API sul database db, senza avviare i job in background
+func newTestRouter(t *testing.T, db database.AppDatabase) http.Handler {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	router, err := New(Config{
+		Logger:                logger,
+		Database:              db,
+		UploadsDirectory:      t.TempDir(),
+		UploadsExpiry:         time.Hour,
+		UploadsMaxSize:        1 << 20,
+		JobsInterval:          time.Minute,
+		TrashRetention:        time.Hour,
+		ViewsWindow:           time.Hour,
+		DuplicatesMode:        duplicatesWarn,
+		DuplicatesMaxDistance: 5,
+		ReactionTypes:         []string{database.DefaultReaction},
+	})
+	if err != nil {
+		t.Fatalf("creating router: %v", err)
+	}
+	t.Cleanup(func() { _ = router.Close() })
+	return router.Handler()
+}
+
+// serveAs esegue sull'handler h la richiesta method path, autenticata come user, e restituisce la risposta registrata
+func serveAs(h http.Handler, user database.User, method string, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, body)
+	r.Header.Set("Authorization", "Bearer "+strconv.Itoa(user.ID))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+)
+
+// Valori di Config.DuplicatesMode: con duplicatesWarn un post quasi identico a una foto dell'utente viene pubblicato
+// indicando le foto simili, con duplicatesReject viene rifiutato
+const (
+	duplicatesWarn   = "warn"
+	duplicatesReject = "reject"
+)
+
+// hashBackfillBatch è il numero di immagini di cui il job hash-images calcola l'hash percettivo a ogni esecuzione
+const hashBackfillBatch = 50
+
+// errDuplicatePhoto indica che l'utente ha già pubblicato una foto quasi identica e la configurazione rifiuta i
+// duplicati
+var errDuplicatePhoto = errors.New("you have already posted a nearly identical photo")
+
+// errBlockedImage indica che un'immagine del post è quasi identica a una bloccata dagli amministratori
+var errBlockedImage = errors.New("the image has been blocked by the administrators")
+
+// hashImages calcola l'hash percettivo di ogni immagine, nello stesso ordine; è vuoto per le immagini che non possono
+// essere decodificate
+func hashImages(images [][]byte) []string {
+	hashes := make([]string, len(images))
+	for i, image := range images {
+		hashes[i] = perceptualHash(image)
+	}
+	return hashes
+}
+
+// isNearDuplicate indica se due hash percettivi appartengono a immagini quasi identiche, cioè differiscono per al più
+// rt.duplicatesMaxDistance bit
+func (rt *_router) isNearDuplicate(a string, b string) bool {
+	distance := hashDistance(a, b)
+	return distance >= 0 && distance <= rt.duplicatesMaxDistance
+}
+
+// checkBlockedImages restituisce errBlockedImage se una delle immagini con gli hash percettivi hashes è quasi identica
+// a una bloccata dagli amministratori
+func (rt *_router) checkBlockedImages(db database.AppDatabase, hashes []string) error {
+	blocked, err := db.GetBlockedHashes()
+	if err != nil {
+		return fmt.Errorf("reading blocked hashes: %w", err)
+	}
+
+	for _, hash := range hashes {
+		for _, blockedHash := range blocked {
+			if rt.isNearDuplicate(hash, blockedHash.Hash) {
+				return errBlockedImage
+			}
+		}
+	}
+
+	return nil
+}
+
+// findDuplicates restituisce gli ID delle foto di user, escluse quelle nel cestino, con un'immagine quasi identica a
+// una di quelle con gli hash percettivi hashes
+func (rt *_router) findDuplicates(db database.AppDatabase, user database.User, hashes []string) ([]int, error) {
+	existing, err := db.GetImageHashesByUserID(strconv.Itoa(user.ID))
+	if err != nil {
+		return nil, fmt.Errorf("reading image hashes: %w", err)
+	}
+
+	var duplicates []int
+	for _, image := range existing {
+		if len(duplicates) > 0 && duplicates[len(duplicates)-1] == image.PhotoID {
+			continue
+		}
+		for _, hash := range hashes {
+			if rt.isNearDuplicate(hash, image.Hash) {
+				duplicates = append(duplicates, image.PhotoID)
+				break
+			}
+		}
+	}
+
+	return duplicates, nil
+}
+
+// joinIDs restituisce gli ID separati da virgole, come nell'header Duplicate-Of degli upload ripristinabili
+func joinIDs(ids []int) string {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	return strings.Join(values, ",")
+}
+
+// hashExistingImages è il job che calcola l'hash percettivo delle immagini caricate prima della sua introduzione, un
+// gruppo alla volta, così che anche le foto già pubblicate vengano confrontate con i nuovi post
+func (rt *_router) hashExistingImages() error {
+	images, err := rt.db.GetUnhashedImages(hashBackfillBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		err = rt.db.SetImageHash(image.PhotoID, image.Position, perceptualHash(image.ImageData))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(images) > 0 {
+		rt.baseLogger.WithField("images", len(images)).Debug("perceptual hashes computed")
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"time"
+)
+
+// backgroundJob is a task executed periodically in background, outside of any request
+type backgroundJob struct {
+	// name identifies the job in logs
+	name string
+
+	// run executes the job once
+	run func() error
+}
+
+// backgroundJobs returns the jobs started by StartJobs
+func (rt *_router) backgroundJobs() []backgroundJob {
+	return []backgroundJob{
+		{name: "expire-uploads", run: rt.expireUploads},
+		{name: "publish-scheduled", run: rt.publishScheduled},
+		{name: "purge-trash", run: rt.purgeTrash},
+		{name: "hash-images", run: rt.hashExistingImages},
+	}
+}
+
+// StartJobs runs every background job now and then every rt.jobsInterval, until Close is called
+func (rt *_router) StartJobs() {
+	rt.jobsWg.Add(1)
+	go func() {
+		defer rt.jobsWg.Done()
+
+		ticker := time.NewTicker(rt.jobsInterval)
+		defer ticker.Stop()
+
+		for {
+			rt.runJobs()
+
+			select {
+			case <-rt.stopJobs:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runJobs executes once every background job. A failing job is logged and retried at the next run.
+func (rt *_router) runJobs() {
+	for _, job := range rt.backgroundJobs() {
+		err := job.run()
+		if err != nil {
+			rt.baseLogger.WithError(err).WithField("job", job.name).Error("background job failed")
+		}
+	}
+}
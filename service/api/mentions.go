@@ -0,0 +1,62 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+)
+
+// mentionPattern individua le menzioni nella forma @username. La @ deve trovarsi all'inizio del testo o dopo un carattere
+// che non fa parte di una parola, così che un indirizzo come mario@example.com non venga considerato una menzione.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.]+)`)
+
+// errMentionBanned indica che uno degli utenti menzionati ha bannato l'autore del testo
+var errMentionBanned = errors.New("mentioned user has banned the author")
+
+// resolveMentions cerca le menzioni @username in text e le risolve sugli utenti esistenti; le menzioni di username che
+// non esistono restano semplice testo. Restituisce errMentionBanned se un utente menzionato ha bannato author.
+func resolveMentions(db database.AppDatabase, author database.User, text string) ([]database.Mention, error) {
+	var mentions []database.Mention
+	checked := make(map[int]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		// match[2]:match[3] è lo username senza @, il punto finale appartiene alla frase e non allo username
+		name := strings.TrimRight(text[match[2]:match[3]], ".")
+		if name == "" {
+			continue
+		}
+		start, end := match[2]-1, match[2]+len(name)
+
+		user, err := db.GetUserByUsername(strings.ToLower(name))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !checked[user.ID] {
+			isBanned, err := db.IsBanned(strconv.Itoa(author.ID), strconv.Itoa(user.ID))
+			if err != nil {
+				return nil, err
+			}
+			if isBanned {
+				return nil, errMentionBanned
+			}
+			checked[user.ID] = true
+		}
+
+		mentions = append(mentions, database.Mention{
+			UserID:   user.ID,
+			Username: user.Username,
+			Offset:   utf8.RuneCountInString(text[:start]),
+			Length:   utf8.RuneCountInString(text[start:end]),
+		})
+	}
+
+	return mentions, nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+)
+
+func TestResolveMentionsRuneOffsets(t *testing.T) {
+	db := newTestDatabase(t)
+	author := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	tests := []struct {
+		name string
+		text string
+		want []database.Mention
+	}{
+		{"ascii", "ciao @bob", []database.Mention{{UserID: bob.ID, Username: "bob", Offset: 5, Length: 4}}},
+		{"accented text before", "caffè con @bob", []database.Mention{{UserID: bob.ID, Username: "bob", Offset: 10, Length: 4}}},
+		{"emoji before", "🙂🙂 @bob", []database.Mention{{UserID: bob.ID, Username: "bob", Offset: 3, Length: 4}}},
+		{"final dot", "grazie @bob.", []database.Mention{{UserID: bob.ID, Username: "bob", Offset: 7, Length: 4}}},
+		{"uppercase", "è @BOB", []database.Mention{{UserID: bob.ID, Username: "bob", Offset: 2, Length: 4}}},
+		{"repeated", "@bob e @bob", []database.Mention{
+			{UserID: bob.ID, Username: "bob", Offset: 0, Length: 4},
+			{UserID: bob.ID, Username: "bob", Offset: 7, Length: 4},
+		}},
+		{"email address", "scrivi a mario@bob.com", nil},
+		{"unknown user", "ciao @nessuno", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mentions, err := resolveMentions(db, author, tt.text)
+			if err != nil {
+				t.Fatalf("resolveMentions: %v", err)
+			}
+			if len(mentions) != len(tt.want) {
+				t.Fatalf("got %d mentions %+v, want %+v", len(mentions), mentions, tt.want)
+			}
+			for i := range mentions {
+				if mentions[i] != tt.want[i] {
+					t.Errorf("mention %d: got %+v, want %+v", i, mentions[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestResolveMentionsBanned(t *testing.T) {
+	db := newTestDatabase(t)
+	author := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	err := db.BanUser(strconv.Itoa(bob.ID), strconv.Itoa(author.ID))
+	if err != nil {
+		t.Fatalf("banning: %v", err)
+	}
+
+	_, err = resolveMentions(db, author, "ciao @bob")
+	if !errors.Is(err, errMentionBanned) {
+		t.Fatalf("got error %v, want errMentionBanned", err)
+	}
+}
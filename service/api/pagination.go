@@ -0,0 +1,38 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// defaultPageLimit e maxPageLimit sono il numero predefinito e massimo di elementi restituiti in una pagina
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// errInvalidPage indica che i parametri di paginazione della richiesta non sono validi
+var errInvalidPage = errors.New("cursor must be a cursor returned in next_cursor, limit a number from 1 to " +
+	strconv.Itoa(maxPageLimit))
+
+// parsePage legge i parametri di paginazione "cursor" e "limit" della richiesta. Il cursore è il next_cursor della
+// pagina precedente, 0 se la richiesta è per la prima pagina.
+func parsePage(r *http.Request) (cursor int, limit int, err error) {
+	limit = defaultPageLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, errInvalidPage
+		}
+	}
+
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		cursor, err = strconv.Atoi(value)
+		if err != nil || cursor < 1 {
+			return 0, 0, errInvalidPage
+		}
+	}
+
+	return cursor, limit, nil
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantCursor int
+		wantLimit  int
+		wantErr    bool
+	}{
+		{"", 0, defaultPageLimit, false},
+		{"?limit=1", 0, 1, false},
+		{"?limit=100", 0, maxPageLimit, false},
+		{"?cursor=42&limit=5", 42, 5, false},
+		{"?limit=0", 0, 0, true},
+		{"?limit=101", 0, 0, true},
+		{"?limit=-1", 0, 0, true},
+		{"?limit=abc", 0, 0, true},
+		{"?cursor=0", 0, 0, true},
+		{"?cursor=-3", 0, 0, true},
+		{"?cursor=abc", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			cursor, limit, err := parsePage(httptest.NewRequest(http.MethodGet, "/"+tt.query, nil))
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidPage) {
+					t.Fatalf("got error %v, want errInvalidPage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePage: %v", err)
+			}
+			if cursor != tt.wantCursor || limit != tt.wantLimit {
+				t.Errorf("got cursor %d limit %d, want cursor %d limit %d", cursor, limit, tt.wantCursor, tt.wantLimit)
+			}
+		})
+	}
+}
+
+// TestPageBoundary verifica, sulla lista dei segnalibri, che una pagina abbia next_cursor solo se esiste almeno un
+// elemento dopo di essa, cioè se la query con limit+1 ne ha restituito uno in più
+func TestPageBoundary(t *testing.T) {
+	db := newTestDatabase(t)
+	h := newTestRouter(t, db)
+	user := newTestUser(t, db, "alice")
+	userID := strconv.Itoa(user.ID)
+
+	getPage := func(t *testing.T, query string) bookmarksPage {
+		t.Helper()
+		w := serveAs(h, user, http.MethodGet, "/users/"+userID+"/bookmarks"+query, nil, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET bookmarks%s: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var page bookmarksPage
+		err := json.NewDecoder(w.Body).Decode(&page)
+		if err != nil {
+			t.Fatalf("decoding page: %v", err)
+		}
+		return page
+	}
+
+	addBookmark := func(t *testing.T) {
+		t.Helper()
+		id, err := db.SetPhoto(userID, [][]byte{[]byte("image")}, []string{""}, "", nil, database.VisibilityPublic,
+			"20260101120000", false)
+		if err != nil {
+			t.Fatalf("creating photo: %v", err)
+		}
+		err = db.SetBookmark(userID, strconv.FormatInt(id, 10), "20260101120000")
+		if err != nil {
+			t.Fatalf("bookmarking: %v", err)
+		}
+	}
+
+	// Esattamente limit elementi: nessuna pagina successiva
+	addBookmark(t)
+	addBookmark(t)
+	page := getPage(t, "?limit=2")
+	if len(page.Bookmarks) != 2 || page.NextCursor != "" {
+		t.Fatalf("2 bookmarks, limit 2: got %d bookmarks and cursor %q, want 2 and no cursor", len(page.Bookmarks),
+			page.NextCursor)
+	}
+
+	// Un elemento in più: la prima pagina ne restituisce limit e il cursore porta al resto, senza ripetizioni
+	addBookmark(t)
+	page = getPage(t, "?limit=2")
+	if len(page.Bookmarks) != 2 || page.NextCursor == "" {
+		t.Fatalf("3 bookmarks, limit 2: got %d bookmarks and cursor %q, want 2 and a cursor", len(page.Bookmarks),
+			page.NextCursor)
+	}
+	if page.NextCursor != strconv.Itoa(page.Bookmarks[1].ID) {
+		t.Errorf("cursor %q is not the ID of the last bookmark %d", page.NextCursor, page.Bookmarks[1].ID)
+	}
+
+	next := getPage(t, "?limit=2&cursor="+page.NextCursor)
+	if len(next.Bookmarks) != 1 || next.NextCursor != "" {
+		t.Fatalf("second page: got %d bookmarks and cursor %q, want 1 and no cursor", len(next.Bookmarks),
+			next.NextCursor)
+	}
+	for _, bookmark := range page.Bookmarks {
+		if bookmark.ID == next.Bookmarks[0].ID {
+			t.Errorf("bookmark %d is on both pages", bookmark.ID)
+		}
+	}
+
+	// Parametri non validi
+	w := serveAs(h, user, http.MethodGet, "/users/"+userID+"/bookmarks?limit=0", nil, "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: got status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+
+	// Formati di immagine di cui si calcola l'hash percettivo
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Il dHash confronta la luminosità di celle adiacenti di una griglia di dHashWidth x dHashHeight: ogni riga dà
+// dHashWidth-1 bit, per un totale di 64
+const (
+	dHashWidth  = 9
+	dHashHeight = 8
+)
+
+// dHashSamples è il numero massimo di pixel letti per lato di ogni cella, così che le immagini grandi non rallentino
+// l'upload
+const dHashSamples = 16
+
+// perceptualHash calcola l'hash percettivo (dHash a 64 bit, in esadecimale) di un'immagine GIF, JPEG o PNG. Immagini
+// quasi identiche, ad esempio ridimensionate o ricompresse, hanno hash che differiscono per pochi bit. Restituisce una
+// stringa vuota se l'immagine non può essere decodificata.
+func perceptualHash(data []byte) string {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < dHashWidth || height < dHashHeight {
+		return ""
+	}
+
+	// Luminosità media di ogni cella, campionando al più dHashSamples pixel per lato
+	var cells [dHashHeight][dHashWidth]float64
+	for cy := 0; cy < dHashHeight; cy++ {
+		y0, y1 := bounds.Min.Y+cy*height/dHashHeight, bounds.Min.Y+(cy+1)*height/dHashHeight
+		stepY := (y1-y0)/dHashSamples + 1
+		for cx := 0; cx < dHashWidth; cx++ {
+			x0, x1 := bounds.Min.X+cx*width/dHashWidth, bounds.Min.X+(cx+1)*width/dHashWidth
+			stepX := (x1-x0)/dHashSamples + 1
+
+			var sum float64
+			var count int
+			for y := y0; y < y1; y += stepY {
+				for x := x0; x < x1; x += stepX {
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					count++
+				}
+			}
+			cells[cy][cx] = sum / float64(count)
+		}
+	}
+
+	var hash uint64
+	for cy := 0; cy < dHashHeight; cy++ {
+		for cx := 0; cx < dHashWidth-1; cx++ {
+			hash <<= 1
+			if cells[cy][cx] < cells[cy][cx+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash)
+}
+
+// parsePerceptualHash legge un hash percettivo in esadecimale; restituisce false se non è valido
+func parsePerceptualHash(hash string) (uint64, bool) {
+	if len(hash) != 16 {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(hash, 16, 64)
+	return value, err == nil
+}
+
+// hashDistance restituisce il numero di bit diversi tra due hash percettivi, o -1 se uno dei due non è valido
+func hashDistance(a string, b string) int {
+	valueA, okA := parsePerceptualHash(a)
+	valueB, okB := parsePerceptualHash(b)
+	if !okA || !okB {
+		return -1
+	}
+	return bits.OnesCount64(valueA ^ valueB)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+)
+
+// errQuotaExceeded è restituito da createPhoto quando il nuovo post supera la quota dell'utente
+var errQuotaExceeded = errors.New("storage quota exceeded")
+
+// quotaStatus descrive la quota di un utente e quanto ne ha già usato. Un limite pari a 0 indica nessun limite. Le foto
+// nel cestino non sono conteggiate in UsedPhotos e UsedBytes.
+type quotaStatus struct {
+	MaxPhotos  int   `json:"max_photos"`
+	MaxBytes   int64 `json:"max_bytes"`
+	UsedPhotos int   `json:"used_photos"`
+	UsedBytes  int64 `json:"used_bytes"`
+	Overridden bool  `json:"overridden"`
+}
+
+// isAdmin indica se user è uno degli amministratori indicati nella configurazione
+func (rt *_router) isAdmin(user database.User) bool {
+	for _, username := range rt.adminUsernames {
+		if user.ID != 0 && strings.EqualFold(username, user.Username) {
+			return true
+		}
+	}
+	return false
+}
+
+// quotaLock restituisce il mutex che serializza i nuovi post dell'utente, così due upload contemporanei non possono
+// superare insieme la quota
+func (rt *_router) quotaLock(userID int) *sync.Mutex {
+	lock, _ := rt.quotaLocks.LoadOrStore(userID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// getQuota restituisce la quota dell'utente userID: i limiti predefiniti, eventualmente sostituiti da quelli scelti da
+// un amministratore, e lo spazio usato
+func (rt *_router) getQuota(db database.AppDatabase, userID string) (quotaStatus, error) {
+	quota := quotaStatus{
+		MaxPhotos: rt.quotaMaxPhotos,
+		MaxBytes:  rt.quotaMaxBytes,
+	}
+
+	override, err := db.GetQuotaOverride(userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return quota, err
+	} else if err == nil {
+		quota.Overridden = true
+		if override.MaxPhotos != nil {
+			quota.MaxPhotos = *override.MaxPhotos
+		}
+		if override.MaxBytes != nil {
+			quota.MaxBytes = *override.MaxBytes
+		}
+	}
+
+	usage, err := db.GetStorageUsage(userID)
+	if err != nil {
+		return quota, err
+	}
+	quota.UsedPhotos = usage.Photos
+	quota.UsedBytes = usage.Bytes
+
+	return quota, nil
+}
+
+// checkQuota verifica che un nuovo post di size byte, o una foto di size byte ripristinata dal cestino, rientri nella
+// quota dell'utente userID. Se non rientra restituisce un errore errQuotaExceeded con il limite superato, da mostrare
+// al client.
+func (rt *_router) checkQuota(db database.AppDatabase, userID int, size int64) error {
+	quota, err := rt.getQuota(db, strconv.Itoa(userID))
+	if err != nil {
+		return fmt.Errorf("reading quota: %w", err)
+	}
+
+	if quota.MaxPhotos > 0 && quota.UsedPhotos+1 > quota.MaxPhotos {
+		return fmt.Errorf("%w: you already have %d of %d photos", errQuotaExceeded, quota.UsedPhotos, quota.MaxPhotos)
+	}
+	if quota.MaxBytes > 0 && quota.UsedBytes+size > quota.MaxBytes {
+		return fmt.Errorf("%w: %d bytes used of %d, the new photo needs %d bytes", errQuotaExceeded, quota.UsedBytes, quota.MaxBytes, size)
+	}
+
+	return nil
+}
@@ -2,5 +2,10 @@ package api
 
 // Close should close everything opened in the lifecycle of the `_router`; for example, background goroutines.
 func (rt *_router) Close() error {
+	// Stop background jobs and wait for the running ones to finish
+	rt.stopOnce.Do(func() {
+		close(rt.stopJobs)
+	})
+	rt.jobsWg.Wait()
 	return nil
 }
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/api/reqcontext"
+	"git.sapienzaapps.it/fantasticcoffee/fantastic-coffee-decaffeinated/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// errInvalidVisibility è restituito da createPhoto quando la visibilità richiesta non è valida
+var errInvalidVisibility = errors.New("visibility must be public, followers or private")
+
+// checkPhotoAccess verifica che user possa vedere la foto photoID di userID: la foto deve esistere, appartenere a
+// userID ed essere visibile a user (404 altrimenti, così non si rivela l'esistenza di foto private) e il proprietario non
+// deve aver bannato user (403). In caso di errore scrive la risposta e restituisce false.
+func checkPhotoAccess(w http.ResponseWriter, ctx reqcontext.RequestContext, user database.User, userID string, photoID string) bool {
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || (err == nil && strconv.Itoa(photo.UserID) != userID) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return false
+	} else if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	isBanned, err := ctx.Database.IsBanned(strconv.Itoa(user.ID), userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	if isBanned {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+
+	visible, err := ctx.Database.IsPhotoVisible(photoID, strconv.Itoa(user.ID))
+	if err != nil {
+		log.Printf("Error checking visibility of photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	}
+
+	if !visible {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return false
+	}
+
+	return true
+}
+
+// loadOwnPhoto restituisce la foto photosId del percorso se appartiene all'utente e non è nel cestino;
+// altrimenti risponde con 404 e restituisce false
+func loadOwnPhoto(w http.ResponseWriter, ps httprouter.Params, ctx reqcontext.RequestContext, user database.User) (database.Photo, bool) {
+	photoID := ps.ByName("photosId")
+	photo, err := ctx.Database.GetPhotoByID(photoID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || (err == nil && (photo.UserID != user.ID || photo.DeletedAt != nil)) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return photo, false
+	} else if err != nil {
+		log.Printf("Error retrieving photo %s: %v", photoID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return photo, false
+	}
+
+	return photo, true
+}
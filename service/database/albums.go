@@ -0,0 +1,305 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// ErrAlbumOrderMismatch è restituito da ReorderAlbumPhotos quando le foto indicate non corrispondono a quelle dell'album
+var ErrAlbumOrderMismatch = errors.New("photo IDs do not match the photos of the album")
+
+// albumColumns sono le colonne lette da scanAlbum, nello stesso ordine. Copertina e numero di foto considerano solo le
+// foto visibili all'utente sql.Named("viewer", ...): se la copertina scelta non è visibile, o non è stata scelta, viene
+// usata la prima foto visibile dell'album.
+const albumColumns = `a.id, a.user_id, a.title,
+	COALESCE(
+		(SELECT photos.id FROM photos WHERE photos.id = a.cover_photo_id AND ` + photoVisibleTo + `),
+		(SELECT ap.photo_id FROM album_photos ap JOIN photos ON photos.id = ap.photo_id
+			WHERE ap.album_id = a.id AND ` + photoVisibleTo + ` ORDER BY ap.position LIMIT 1)),
+	a.timestamp,
+	(SELECT COUNT(*) FROM album_photos ap JOIN photos ON photos.id = ap.photo_id WHERE ap.album_id = a.id AND ` + photoVisibleTo + `)`
+
+// scanAlbum legge un album selezionato con albumColumns
+func scanAlbum(row rowScanner) (Album, error) {
+	var album Album
+	err := row.Scan(&album.ID, &album.UserID, &album.Title, &album.CoverPhotoID, &album.Timestamp, &album.NumPhotos)
+	return album, err
+}
+
+// SetAlbum crea un nuovo album vuoto per l'utente userID
+func (a *appdbimpl) SetAlbum(userID string, title string, coverPhotoID *int, timestamp string) (int64, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return 0, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`INSERT INTO albums (user_id, title, cover_photo_id, timestamp) VALUES (?, ?, ?, ?)`, UserID, title, coverPhotoID, timestamp)
+	if err != nil {
+		return 0, fmt.Errorf("inserting album: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("getting last insert ID: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetAlbumByID restituisce l'album con id=albumID insieme alle sue foto visibili all'utente viewerID, nell'ordine
+// scelto dal proprietario
+func (a *appdbimpl) GetAlbumByID(albumID string, viewerID string) (Album, error) {
+
+	AlbumID, err := strconv.Atoi(albumID)
+	if err != nil {
+		return Album{}, fmt.Errorf("converting album ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return Album{}, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	album, err := scanAlbum(a.c.QueryRow(`SELECT `+albumColumns+` FROM albums a WHERE a.id = :album`,
+		sql.Named("album", AlbumID), sql.Named("viewer", ViewerID)))
+	if err != nil {
+		return album, fmt.Errorf("selecting album: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT `+photoColumns+` FROM album_photos ap JOIN photos ON photos.id = ap.photo_id
+		WHERE ap.album_id = :album AND `+photoVisibleTo+` ORDER BY ap.position`,
+		sql.Named("album", AlbumID), sql.Named("viewer", ViewerID))
+	if err != nil {
+		return album, fmt.Errorf("selecting album photos: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return album, fmt.Errorf("scanning photo: %w", err)
+		}
+		album.Photos = append(album.Photos, photo)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return album, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	err = a.attachPhotoMentions(album.Photos)
+	if err != nil {
+		return album, err
+	}
+	err = a.attachSavedByMe(album.Photos, ViewerID)
+	if err != nil {
+		return album, err
+	}
+
+	err = a.attachLikedByMe(album.Photos, ViewerID)
+	if err != nil {
+		return album, err
+	}
+
+	return album, nil
+}
+
+// GetAlbumsByUserID restituisce gli album dell'utente userID, senza le foto, dal più recente. Copertine e conteggi
+// considerano solo le foto visibili all'utente viewerID.
+func (a *appdbimpl) GetAlbumsByUserID(userID string, viewerID string) ([]Album, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT `+albumColumns+` FROM albums a WHERE a.user_id = :user ORDER BY a.id DESC`,
+		sql.Named("user", UserID), sql.Named("viewer", ViewerID))
+	if err != nil {
+		return nil, fmt.Errorf("selecting albums: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var albums []Album
+	for rows.Next() {
+		album, err := scanAlbum(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning album: %w", err)
+		}
+		albums = append(albums, album)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return albums, nil
+}
+
+// UpdateAlbum modifica titolo e copertina dell'album; con coverPhotoID nil la copertina torna ad essere la prima foto
+func (a *appdbimpl) UpdateAlbum(albumID string, title string, coverPhotoID *int) error {
+
+	AlbumID, err := strconv.Atoi(albumID)
+	if err != nil {
+		return fmt.Errorf("converting album ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE albums SET title = ?, cover_photo_id = ? WHERE id = ?`, title, coverPhotoID, AlbumID)
+	if err != nil {
+		return fmt.Errorf("updating album: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAlbum elimina l'album; le foto che conteneva non vengono toccate
+func (a *appdbimpl) DeleteAlbum(albumID string) error {
+
+	AlbumID, err := strconv.Atoi(albumID)
+	if err != nil {
+		return fmt.Errorf("converting album ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`DELETE FROM album_photos WHERE album_id = ?`, AlbumID)
+	if err != nil {
+		return fmt.Errorf("deleting album photos: %w", err)
+	}
+
+	_, err = a.c.Exec(`DELETE FROM albums WHERE id = ?`, AlbumID)
+	if err != nil {
+		return fmt.Errorf("deleting album: %w", err)
+	}
+
+	return nil
+}
+
+// AddPhotoToAlbum aggiunge la foto in fondo all'album; se la foto è già nell'album non fa nulla
+func (a *appdbimpl) AddPhotoToAlbum(albumID string, photoID string) error {
+
+	AlbumID, err := strconv.Atoi(albumID)
+	if err != nil {
+		return fmt.Errorf("converting album ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT OR IGNORE INTO album_photos (album_id, photo_id, position)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), -1) + 1 FROM album_photos WHERE album_id = ?))`, AlbumID, PhotoID, AlbumID)
+	if err != nil {
+		return fmt.Errorf("inserting album photo: %w", err)
+	}
+
+	return nil
+}
+
+// RemovePhotoFromAlbum toglie la foto dall'album senza eliminarla
+func (a *appdbimpl) RemovePhotoFromAlbum(albumID string, photoID string) error {
+
+	AlbumID, err := strconv.Atoi(albumID)
+	if err != nil {
+		return fmt.Errorf("converting album ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`DELETE FROM album_photos WHERE album_id = ? AND photo_id = ?`, AlbumID, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting album photo: %w", err)
+	}
+
+	return nil
+}
+
+// ReorderAlbumPhotos riordina le foto dell'album secondo photoIDs, che deve contenere esattamente le foto dell'album,
+// ciascuna una sola volta. Altrimenti restituisce ErrAlbumOrderMismatch.
+func (a *appdbimpl) ReorderAlbumPhotos(albumID string, photoIDs []int) error {
+
+	AlbumID, err := strconv.Atoi(albumID)
+	if err != nil {
+		return fmt.Errorf("converting album ID to integer: %w", err)
+	}
+
+	seen := make(map[int]bool)
+	for _, photoID := range photoIDs {
+		if seen[photoID] {
+			return ErrAlbumOrderMismatch
+		}
+		seen[photoID] = true
+	}
+
+	tx, err := a.c.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var count int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM album_photos WHERE album_id = ?`, AlbumID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("counting album photos: %w", err)
+	}
+	if count != len(photoIDs) {
+		err = ErrAlbumOrderMismatch
+		return err
+	}
+
+	for position, photoID := range photoIDs {
+		var result sql.Result
+		result, err = tx.Exec(`UPDATE album_photos SET position = ? WHERE album_id = ? AND photo_id = ?`, position, AlbumID, photoID)
+		if err != nil {
+			return fmt.Errorf("updating album photo position: %w", err)
+		}
+
+		var updated int64
+		updated, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("getting affected rows: %w", err)
+		}
+		if updated != 1 {
+			// La foto non appartiene all'album
+			err = ErrAlbumOrderMismatch
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing album order: %w", err)
+	}
+
+	return nil
+}
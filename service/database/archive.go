@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// ArchivePhoto archivia la foto photoID, con archivedAt (formato YYYYMMDDHHmmSS) come momento dell'archiviazione. Una
+// foto archiviata resta visibile solo al proprietario e mantiene likes e commenti; archiviarla di nuovo non ha effetto.
+func (a *appdbimpl) ArchivePhoto(photoID string, archivedAt string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE photos SET archived_at = ? WHERE id = ? AND archived_at IS NULL`, archivedAt, PhotoID)
+	if err != nil {
+		return fmt.Errorf("archiving photo: %w", err)
+	}
+
+	return nil
+}
+
+// UnarchivePhoto toglie dall'archivio la foto photoID, che torna visibile secondo la sua visibilità
+func (a *appdbimpl) UnarchivePhoto(photoID string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE photos SET archived_at = NULL WHERE id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("unarchiving photo: %w", err)
+	}
+
+	return nil
+}
+
+// GetArchivedPhotosByUserID restituisce le foto archiviate dell'utente userID, dall'ultima archiviata; le foto nel
+// cestino sono escluse
+func (a *appdbimpl) GetArchivedPhotosByUserID(userID string) ([]Photo, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT `+photoColumns+` FROM photos WHERE photos.user_id = ? AND photos.archived_at IS NOT NULL
+		AND photos.deleted_at IS NULL ORDER BY photos.archived_at DESC, photos.id DESC`, UserID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting archived photos: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var photos []Photo
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	err = a.attachPhotoMentions(photos)
+	if err != nil {
+		return nil, err
+	}
+	err = a.attachSavedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.attachLikedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
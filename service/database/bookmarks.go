@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// SetBookmark salva la foto photoID tra le foto salvate dall'utente userID; salvarla di nuovo non ha effetto
+func (a *appdbimpl) SetBookmark(userID string, photoID string, timestamp string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT OR IGNORE INTO bookmarks (user_id, photo_id, timestamp) VALUES (?, ?, ?)`,
+		UserID, PhotoID, timestamp)
+	if err != nil {
+		return fmt.Errorf("inserting bookmark: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBookmark toglie la foto photoID dalle foto salvate dall'utente userID; restituisce sql.ErrNoRows se la foto
+// non era salvata
+func (a *appdbimpl) DeleteBookmark(userID string, photoID string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`DELETE FROM bookmarks WHERE user_id = ? AND photo_id = ?`, UserID, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting bookmark: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetBookmarksByUserID restituisce al massimo limit foto salvate dall'utente userID, dall'ultima salvata; se before è
+// maggiore di 0 restituisce solo i salvataggi con ID minore di before. Sono escluse le foto che l'utente non può più
+// vedere, comprese quelle dei proprietari che lo hanno bannato.
+func (a *appdbimpl) GetBookmarksByUserID(userID string, before int, limit int) ([]Bookmark, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT `+photoColumns+`, bookmarks.id, bookmarks.timestamp
+		FROM bookmarks JOIN photos ON photos.id = bookmarks.photo_id
+		WHERE bookmarks.user_id = :viewer AND (:before = 0 OR bookmarks.id < :before) AND `+photoVisibleTo+`
+		AND NOT EXISTS (SELECT 1 FROM bans WHERE bans.user_id = photos.user_id AND bans.banned_id = :viewer)
+		ORDER BY bookmarks.id DESC LIMIT :limit`,
+		sql.Named("viewer", UserID), sql.Named("before", before), sql.Named("limit", limit))
+	if err != nil {
+		return nil, fmt.Errorf("selecting bookmarks: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var bookmarks []Bookmark
+	var photos []Photo
+	for rows.Next() {
+		var bookmark Bookmark
+		bookmark.Photo, err = scanPhoto(rows, &bookmark.ID, &bookmark.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("scanning bookmark: %w", err)
+		}
+		bookmark.Photo.SavedByMe = true
+		bookmarks = append(bookmarks, bookmark)
+		photos = append(photos, bookmark.Photo)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	err = a.attachPhotoMentions(photos)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.attachLikedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range bookmarks {
+		bookmarks[i].Photo.Mentions = photos[i].Mentions
+		bookmarks[i].Photo.LikedByMe = photos[i].LikedByMe
+	}
+
+	return bookmarks, nil
+}
+
+// attachSavedByMe valorizza il campo SavedByMe delle foto, vero per quelle salvate dall'utente viewerID
+func (a *appdbimpl) attachSavedByMe(photos []Photo, viewerID int) error {
+	if len(photos) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(photos)+1)
+	args = append(args, viewerID)
+	for _, photo := range photos {
+		args = append(args, photo.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(photos)), ",")
+
+	rows, err := a.c.Query(`SELECT photo_id FROM bookmarks WHERE user_id = ? AND photo_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("selecting bookmarks: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	saved := make(map[int]bool)
+	for rows.Next() {
+		var photoID int
+		err = rows.Scan(&photoID)
+		if err != nil {
+			return fmt.Errorf("scanning bookmark: %w", err)
+		}
+		saved[photoID] = true
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("iterating rows: %w", err)
+	}
+
+	for i := range photos {
+		photos[i].SavedByMe = saved[photos[i].ID]
+	}
+
+	return nil
+}
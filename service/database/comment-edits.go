@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// EditComment sostituisce il testo e le menzioni del commento commentID, modificato al momento editedAt. Il testo
+// precedente viene conservato in comment_edits.
+func (a *appdbimpl) EditComment(commentID string, text string, mentions []Mention, editedAt string) error {
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	tx, err := a.c.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Una versione è stata scritta alla creazione del commento o alla modifica precedente
+	_, err = tx.Exec(`INSERT INTO comment_edits (comment_id, text, timestamp)
+		SELECT id, text, COALESCE(edited_at, timestamp) FROM comments WHERE id = ?`, CommentID)
+	if err != nil {
+		return fmt.Errorf("saving previous version: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE comments SET text = ?, edited_at = ? WHERE id = ?`, text, editedAt, CommentID)
+	if err != nil {
+		return fmt.Errorf("updating comment: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM mentions WHERE comment_id = ?`, CommentID)
+	if err != nil {
+		return fmt.Errorf("deleting mentions: %w", err)
+	}
+
+	err = insertMentions(tx, nil, CommentID, mentions)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing comment edit: %w", err)
+	}
+
+	return nil
+}
+
+// GetCommentHistory restituisce le versioni precedenti del testo del commento commentID, dalla meno recente
+func (a *appdbimpl) GetCommentHistory(commentID string) ([]CommentVersion, error) {
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return nil, fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT text, timestamp FROM comment_edits WHERE comment_id = ? ORDER BY id`, CommentID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting comment edits: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var versions []CommentVersion
+	for rows.Next() {
+		var version CommentVersion
+		err = rows.Scan(&version.Text, &version.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("scanning comment edit: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// SetCommentReaction imposta la reazione dell'utente userID al commento commentID, sostituendo quella di tipo diverso
+// che aveva già messo; mettere di nuovo la stessa reazione non ha effetto. I like sono le reazioni di tipo
+// DefaultReaction.
+func (a *appdbimpl) SetCommentReaction(userID string, commentID string, reaction string, timestamp string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT INTO comment_likes (comment_id, user_id, reaction, timestamp) VALUES (?, ?, ?, ?)
+		ON CONFLICT (comment_id, user_id) DO UPDATE SET reaction = excluded.reaction, timestamp = excluded.timestamp
+		WHERE comment_likes.reaction != excluded.reaction`, CommentID, UserID, reaction, timestamp)
+	if err != nil {
+		return fmt.Errorf("setting comment reaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCommentReaction toglie la reazione dell'utente userID al commento commentID; restituisce sql.ErrNoRows se
+// l'utente non aveva messo una reazione di tipo reaction
+func (a *appdbimpl) DeleteCommentReaction(userID string, commentID string, reaction string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`DELETE FROM comment_likes WHERE comment_id = ? AND user_id = ? AND reaction = ?`,
+		CommentID, UserID, reaction)
+	if err != nil {
+		return fmt.Errorf("deleting comment reaction: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// attachCommentReactions valorizza il campo Reactions dei commenti con il numero di reazioni di ogni tipo ricevuto
+func (a *appdbimpl) attachCommentReactions(comments []Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(comments))
+	byID := make(map[int]map[string]int, len(comments))
+	for i, comment := range comments {
+		args[i] = comment.ID
+		comments[i].Reactions = make(map[string]int)
+		byID[comment.ID] = comments[i].Reactions
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(comments)), ",")
+
+	rows, err := a.c.Query(`SELECT comment_id, reaction, COUNT(*) FROM comment_likes WHERE comment_id IN (`+
+		placeholders+`) GROUP BY comment_id, reaction`, args...)
+	if err != nil {
+		return fmt.Errorf("counting comment reactions: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	for rows.Next() {
+		var commentID, count int
+		var reaction string
+		err = rows.Scan(&commentID, &reaction, &count)
+		if err != nil {
+			return fmt.Errorf("scanning comment reaction count: %w", err)
+		}
+		byID[commentID][reaction] = count
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return nil
+}
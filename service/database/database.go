@@ -48,31 +48,164 @@ type AppDatabase interface {
 	DeleteUser(username string) error
 	FollowUser(userID string, followedUserID string) error
 	UnfollowUser(userID string, followedUserID string) error
-	GetFollowers(userID string) ([]User, error)
-	GetFollows(userID string) ([]User, error)
+	GetFollowers(userID string, viewerID string, prefix string, after int, limit int) ([]Follow, error)
+	GetFollows(userID string, viewerID string, prefix string, after int, limit int) ([]Follow, error)
 	GetBans(userID string) ([]User, error)
 	BanUser(userID string, bannedUserID string) error
 	UnbanUser(userID string, bannedUserID string) error
 	IsBanned(userID string, otherUserID string) (bool, error)
 	IsFollowed(userID string, otherUserID string) (bool, error)
+	GetRelationship(userID string, otherUserID string) (Relationship, error)
 	CountFollowersByUserID(userID string) (int, error)
 	CountFollowsByUserID(userID string) (int, error)
-	SetPhoto(userId string, image_data []byte, timestamp string) (int64, error)
+	SetPhoto(userId string, images [][]byte, hashes []string, caption string, mentions []Mention, visibility string, timestamp string, scheduled bool) (int64, error)
 	GetPhotoByID(photoID string) (Photo, error)
+	GetPhotoDetails(photoID string, viewerID string) (PhotoDetails, error)
+	GetPhotoImage(photoID string, position int) ([]byte, error)
 	DeletePhoto(photoID string) error
-	SetComment(userId string, photoID string, comment string, timestamp string) (int64, error)
-	GetCommentByID(commentID string) (Comment, error)
-	DeleteComment(commentID string) error
-	GetCommentsByPhotoID(photoID string) ([]Comment, error)
-	GetPhotosByUserID(userId string) ([]Photo, error)
-	SetLike(userId string, photoID string) error
+	SetComment(userId string, photoID string, parentID *int, comment string, mentions []Mention, timestamp string) (int64, error)
+	GetCommentByID(commentID string, viewerID string) (Comment, error)
+	DeleteComment(commentID string, deletedAt string) error
+	GetCommentsByPhotoID(photoID string, viewerID string, newestFirst bool, after int, limit int) ([]Comment, error)
+	GetPinnedCommentsByPhotoID(photoID string, viewerID string) ([]Comment, error)
+	GetFirstCommentsByPhotoID(photoID string, viewerID string, limit int) ([]Comment, error)
+	GetPhotosByUserID(userId string, viewerID string) ([]Photo, error)
 	DeleteLike(likeID string) error
 	GetLikeByID(likeID string) (Like, error)
 	GetLikesByPhotoID(photoID string) ([]Like, error)
 	GetPhotosStreamByUserID(userID string) ([]Photo, error)
 	CountCommentsByPhotoID(photoID string) (int, error)
 	CountLikesByPhotoID(photoID string) (int, error)
-	CountPhotosByUserID(userID string) (int, error)
+	CountPhotosByUserID(userID string, viewerID string) (int, error)
+
+	// Comment replies
+
+	GetRepliesByCommentID(commentID string, viewerID string, after int, limit int) ([]Comment, error)
+
+	// Comment edits
+
+	EditComment(commentID string, text string, mentions []Mention, editedAt string) error
+	GetCommentHistory(commentID string) ([]CommentVersion, error)
+
+	// Comment moderation
+
+	SetPhotoCommentPolicy(photoID string, policy string) error
+	HideComment(commentID string, hiddenAt string) error
+	UnhideComment(commentID string) error
+	PinComment(commentID string, pinnedAt string) error
+	UnpinComment(commentID string) error
+	CountPinnedComments(photoID string) (int, error)
+
+	// Comment reactions
+
+	SetCommentReaction(userID string, commentID string, reaction string, timestamp string) error
+	DeleteCommentReaction(userID string, commentID string, reaction string) error
+
+	// Reactions
+
+	SetReaction(userID string, photoID string, reaction string, timestamp string) error
+	DeleteReaction(userID string, photoID string, reaction string) error
+	CountReactionsByPhotoID(photoID string) (map[string]int, error)
+	GetReactionsByPhotoID(photoID string, viewerID string, reaction string, after int, limit int) ([]Reaction, error)
+
+	// Visibility
+
+	IsPhotoVisible(photoID string, viewerID string) (bool, error)
+	SetPhotoVisibility(photoID string, visibility string) error
+	GetDefaultVisibility(userID string) (string, error)
+	SetDefaultVisibility(userID string, visibility string) error
+
+	// Scheduled photos
+
+	GetScheduledPhotosByUserID(userID string) ([]Photo, error)
+	ReschedulePhoto(photoID string, publishAt string) error
+	PublishDuePhotos(now string) (int64, error)
+
+	// Trash
+
+	TrashPhoto(photoID string, deletedAt string) error
+	RestorePhoto(photoID string) error
+	GetTrashedPhotosByUserID(userID string) ([]Photo, error)
+	GetPhotosDeletedBefore(deletedBefore string) ([]int, error)
+
+	// Archive
+
+	ArchivePhoto(photoID string, archivedAt string) error
+	UnarchivePhoto(photoID string) error
+	GetArchivedPhotosByUserID(userID string) ([]Photo, error)
+
+	// Bookmarks
+
+	SetBookmark(userID string, photoID string, timestamp string) error
+	DeleteBookmark(userID string, photoID string) error
+	GetBookmarksByUserID(userID string, before int, limit int) ([]Bookmark, error)
+
+	// Reposts
+
+	SetRepost(userID string, photoID string, comment string, timestamp string) error
+	GetRepost(userID string, photoID string) (Repost, error)
+	DeleteRepost(userID string, photoID string) error
+	CountRepostsByPhotoID(photoID string) (int, error)
+
+	// Views and insights
+
+	RecordPhotoView(photoID string, viewerID string, timestamp string, dedupSince string) error
+	GetPhotoInsights(photoID string, since string) (Insights, error)
+	GetUserInsights(userID string, since string) (Insights, error)
+
+	// Perceptual hashes
+
+	GetImageHashesByUserID(userID string) ([]ImageHash, error)
+	GetUnhashedImages(limit int) ([]PhotoImage, error)
+	SetImageHash(photoID int, position int, hash string) error
+	GetBlockedHashes() ([]BlockedHash, error)
+	SetBlockedHash(blocked BlockedHash) error
+	DeleteBlockedHash(hash string) error
+
+	// Private accounts
+
+	IsPrivate(userID string) (bool, error)
+	SetPrivate(userID string, private bool) error
+	SetFollowRequest(requesterID string, targetID string, timestamp string) error
+	HasFollowRequest(requesterID string, targetID string) (bool, error)
+	GetFollowRequestsByTarget(targetID string) ([]FollowRequest, error)
+	GetFollowRequestsByRequester(requesterID string) ([]FollowRequest, error)
+	ApproveFollowRequest(requesterID string, targetID string) error
+	DeleteFollowRequest(requesterID string, targetID string) error
+
+	// Mutes
+
+	MuteUser(userID string, mutedID string, timestamp string) error
+	UnmuteUser(userID string, mutedID string) error
+	GetMutesByUserID(userID string, before int, limit int) ([]Mute, error)
+
+	// Uploads
+
+	SetUpload(upload Upload) error
+	GetUploadByID(uploadID string) (Upload, error)
+	UpdateUploadOffset(uploadID string, offset int64, expiresAt string) error
+	SetUploadPhoto(uploadID string, photoID int64) error
+	DeleteUpload(uploadID string) error
+	GetExpiredUploads(now string) ([]Upload, error)
+
+	// Quotas
+
+	GetStorageUsage(userID string) (StorageUsage, error)
+	GetPhotoSize(photoID string) (int64, error)
+	GetQuotaOverride(userID string) (QuotaOverride, error)
+	SetQuotaOverride(override QuotaOverride) error
+	DeleteQuotaOverride(userID string) error
+
+	// Albums
+
+	SetAlbum(userID string, title string, coverPhotoID *int, timestamp string) (int64, error)
+	GetAlbumByID(albumID string, viewerID string) (Album, error)
+	GetAlbumsByUserID(userID string, viewerID string) ([]Album, error)
+	UpdateAlbum(albumID string, title string, coverPhotoID *int) error
+	DeleteAlbum(albumID string) error
+	AddPhotoToAlbum(albumID string, photoID string) error
+	RemovePhotoFromAlbum(albumID string, photoID string) error
+	ReorderAlbumPhotos(albumID string, photoIDs []int) error
 
 	// Ping checks if the database is reachable
 
@@ -100,11 +233,21 @@ func New(db *sql.DB) (AppDatabase, error) {
 	// User table
 	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS users (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		username TEXT NOT NULL UNIQUE
+		username TEXT NOT NULL UNIQUE,
+		default_visibility TEXT NOT NULL DEFAULT 'public',
+		private INTEGER NOT NULL DEFAULT 0
 	)`)
 	if err != nil {
 		return nil, fmt.Errorf("creating table: %w", err)
 	}
+	err = addColumnIfMissing(db, "users", "default_visibility", "TEXT NOT NULL DEFAULT 'public'")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "users", "private", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, err
+	}
 
 	// Photo table
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS photos (
@@ -112,12 +255,42 @@ func New(db *sql.DB) (AppDatabase, error) {
 		user_id INTEGER NOT NULL,
 		image_data BLOB,
 		timestamp TEXT NOT NULL,
+		caption TEXT NOT NULL DEFAULT '',
+		visibility TEXT NOT NULL DEFAULT 'public',
+		publish_at TEXT,
+		deleted_at TEXT,
+		archived_at TEXT,
 		FOREIGN KEY (user_id) REFERENCES users(id)
 	)`)
 	if err != nil {
 		return nil, fmt.Errorf("creating table: %w", err)
 	}
 
+	err = addColumnIfMissing(db, "photos", "caption", "TEXT NOT NULL DEFAULT ''")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "photos", "visibility", "TEXT NOT NULL DEFAULT 'public'")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "photos", "publish_at", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "photos", "deleted_at", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "photos", "archived_at", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "photos", "comment_policy", "TEXT NOT NULL DEFAULT 'everyone'")
+	if err != nil {
+		return nil, err
+	}
+
 	// Comment table
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS comments (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -132,6 +305,67 @@ func New(db *sql.DB) (AppDatabase, error) {
 		return nil, fmt.Errorf("creating table: %w", err)
 	}
 
+	// parent_id è il commento a cui risponde un commento, NULL per i commenti principali; deleted_at è il momento in cui
+	// un commento con risposte è stato eliminato e ne resta solo un segnaposto
+	err = addColumnIfMissing(db, "comments", "parent_id", "INTEGER REFERENCES comments(id)")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "comments", "deleted_at", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS comments_parent ON comments (parent_id)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating index: %w", err)
+	}
+	err = addColumnIfMissing(db, "comments", "edited_at", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+
+	// hidden_at e pinned_at sono impostati dal proprietario della foto quando nasconde un commento o lo fissa in alto
+	err = addColumnIfMissing(db, "comments", "hidden_at", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+	err = addColumnIfMissing(db, "comments", "pinned_at", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+
+	// comment_edits table: le versioni precedenti dei commenti modificati, ognuna con il momento in cui era stata scritta
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS comment_edits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		comment_id INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		timestamp TEXT NOT NULL,
+		FOREIGN KEY (comment_id) REFERENCES comments(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// comment_likes table: le reazioni ai commenti, al più una per utente e commento
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS comment_likes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		comment_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		timestamp TEXT NOT NULL,
+		UNIQUE (comment_id, user_id),
+		FOREIGN KEY (comment_id) REFERENCES comments(id),
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// I like ai commenti messi prima dell'introduzione delle reazioni restano reazioni di tipo DefaultReaction
+	err = addColumnIfMissing(db, "comment_likes", "reaction", "TEXT NOT NULL DEFAULT '"+DefaultReaction+"'")
+	if err != nil {
+		return nil, err
+	}
+
 	// Like table
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS likes (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -144,6 +378,73 @@ func New(db *sql.DB) (AppDatabase, error) {
 		return nil, fmt.Errorf("creating table: %w", err)
 	}
 
+	// likes.timestamp è NULL per i like messi prima della sua introduzione
+	err = addColumnIfMissing(db, "likes", "timestamp", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+
+	// Ogni riga di likes è la reazione di un utente a una foto, al più una per utente e foto: i like messi prima
+	// dell'introduzione delle reazioni restano reazioni di tipo DefaultReaction e quelli ripetuti vengono eliminati
+	err = addColumnIfMissing(db, "likes", "reaction", "TEXT NOT NULL DEFAULT '"+DefaultReaction+"'")
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`DELETE FROM likes WHERE id NOT IN (SELECT MIN(id) FROM likes GROUP BY user_id, photo_id)`)
+	if err != nil {
+		return nil, fmt.Errorf("deleting repeated likes: %w", err)
+	}
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS likes_user_photo ON likes (user_id, photo_id)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating index: %w", err)
+	}
+
+	// photo_views table: le visualizzazioni delle foto da parte di utenti diversi dal proprietario
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS photo_views (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		photo_id INTEGER NOT NULL,
+		viewer_id INTEGER NOT NULL,
+		timestamp TEXT NOT NULL,
+		FOREIGN KEY (photo_id) REFERENCES photos(id),
+		FOREIGN KEY (viewer_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS photo_views_photo ON photo_views (photo_id, viewer_id, timestamp)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating index: %w", err)
+	}
+
+	// bookmarks table: le foto salvate da ogni utente
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS bookmarks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		photo_id INTEGER NOT NULL,
+		timestamp TEXT NOT NULL,
+		UNIQUE (user_id, photo_id),
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (photo_id) REFERENCES photos(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// reposts table: le foto ricondivise da ogni utente ai propri follower
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS reposts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		photo_id INTEGER NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		timestamp TEXT NOT NULL,
+		UNIQUE (user_id, photo_id),
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (photo_id) REFERENCES photos(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
 	// followers table
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS followers (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -168,11 +469,198 @@ func New(db *sql.DB) (AppDatabase, error) {
 		return nil, fmt.Errorf("creating table: %w", err)
 	}
 
+	// photo_images table: le immagini di una foto (post), in ordine di position. Una foto può averne più d'una.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS photo_images (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		photo_id INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		image_data BLOB NOT NULL,
+		UNIQUE (photo_id, position),
+		FOREIGN KEY (photo_id) REFERENCES photos(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	err = migratePhotoImages(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// phash è l'hash percettivo dell'immagine: NULL se non è ancora stato calcolato, vuoto se l'immagine non può essere
+	// decodificata
+	err = addColumnIfMissing(db, "photo_images", "phash", "TEXT")
+	if err != nil {
+		return nil, err
+	}
+
+	// mentions table: una menzione appartiene alla didascalia di una foto (photo_id) oppure a un commento (comment_id)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS mentions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		photo_id INTEGER,
+		comment_id INTEGER,
+		text_offset INTEGER NOT NULL,
+		text_length INTEGER NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (photo_id) REFERENCES photos(id),
+		FOREIGN KEY (comment_id) REFERENCES comments(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// albums table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS albums (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		cover_photo_id INTEGER,
+		timestamp TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (cover_photo_id) REFERENCES photos(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// album_photos table: la stessa foto può appartenere a più album, position ne determina l'ordine nell'album
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS album_photos (
+		album_id INTEGER NOT NULL,
+		photo_id INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (album_id, photo_id),
+		FOREIGN KEY (album_id) REFERENCES albums(id),
+		FOREIGN KEY (photo_id) REFERENCES photos(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// uploads table: upload ripristinabili in corso, i dati ricevuti sono in un file temporaneo fuori dal database
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS uploads (
+		id TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		length INTEGER NOT NULL,
+		upload_offset INTEGER NOT NULL DEFAULT 0,
+		metadata TEXT NOT NULL DEFAULT '',
+		expires_at TEXT NOT NULL,
+		photo_id INTEGER,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (photo_id) REFERENCES photos(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// follow_requests table: richieste di follow verso account privati in attesa di approvazione
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS follow_requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		requester_id INTEGER NOT NULL,
+		target_id INTEGER NOT NULL,
+		timestamp TEXT NOT NULL,
+		UNIQUE (requester_id, target_id),
+		FOREIGN KEY (requester_id) REFERENCES users(id),
+		FOREIGN KEY (target_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// mutes table: utenti silenziati da ogni utente, di cui non vede più foto nello stream e commenti
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS mutes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		muted_id INTEGER NOT NULL,
+		timestamp TEXT NOT NULL,
+		UNIQUE (user_id, muted_id),
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (muted_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// user_quotas table: limiti di spazio scelti dagli amministratori per singoli utenti; un valore NULL indica che
+	// per quel limite vale il default della configurazione
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS user_quotas (
+		user_id INTEGER PRIMARY KEY,
+		max_photos INTEGER,
+		max_bytes INTEGER,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	// blocked_hashes table: hash percettivi di immagini che gli amministratori non permettono di pubblicare
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS blocked_hashes (
+		hash TEXT PRIMARY KEY,
+		reason TEXT NOT NULL DEFAULT '',
+		timestamp TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
 	return &appdbimpl{
 		c: db,
 	}, nil
 }
 
+// migratePhotoImages trasforma le foto salvate prima dell'introduzione di photo_images, che hanno l'immagine in
+// photos.image_data, in foto con una sola immagine.
+func migratePhotoImages(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(`INSERT INTO photo_images (photo_id, position, image_data)
+		SELECT id, 0, image_data FROM photos
+		WHERE image_data IS NOT NULL AND id NOT IN (SELECT photo_id FROM photo_images)`)
+	if err != nil {
+		return fmt.Errorf("migrating photo images: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE photos SET image_data = NULL WHERE image_data IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("migrating photo images: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing photo images migration: %w", err)
+	}
+
+	return nil
+}
+
+// addColumnIfMissing aggiunge la colonna column alla tabella table se non esiste ancora, così che i database creati con
+// una versione precedente dello schema vengano aggiornati all'avvio.
+func addColumnIfMissing(db *sql.DB, table string, column string, definition string) error {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)`, table, column).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("checking column %s.%s: %w", table, column, err)
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	if err != nil {
+		return fmt.Errorf("adding column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
 func (a *appdbimpl) Ping() error {
 	return a.c.Ping()
 }
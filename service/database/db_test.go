@@ -0,0 +1,56 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testTimestamp è il momento usato dai test per creare foto e commenti
+const testTimestamp = "20260101120000"
+
+// newTestDB apre un database SQLite vuoto nella directory temporanea del test
+func newTestDB(t *testing.T) *appdbimpl {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	db, err := New(conn)
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	return db.(*appdbimpl)
+}
+
+// newTestUser crea l'utente name e ne restituisce l'ID
+func newTestUser(t *testing.T, db *appdbimpl, name string) string {
+	t.Helper()
+
+	err := db.SetUser(name)
+	if err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	user, err := db.GetUserByUsername(name)
+	if err != nil {
+		t.Fatalf("reading user %s: %v", name, err)
+	}
+	return strconv.Itoa(user.ID)
+}
+
+// newTestPhoto crea una foto di userID con un'immagine e ne restituisce l'ID
+func newTestPhoto(t *testing.T, db *appdbimpl, userID string, visibility string, scheduled bool) string {
+	t.Helper()
+
+	id, err := db.SetPhoto(userID, [][]byte{[]byte("image")}, []string{""}, "", nil, visibility, testTimestamp, scheduled)
+	if err != nil {
+		t.Fatalf("creating photo: %v", err)
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// followRequestColumns sono le colonne lette da scanFollowRequest, nello stesso ordine
+const followRequestColumns = `fr.id, fr.requester_id, ru.username, fr.target_id, tu.username, fr.timestamp`
+
+// followRequestTables sono le tabelle da cui leggere followRequestColumns
+const followRequestTables = `follow_requests fr
+	JOIN users ru ON ru.id = fr.requester_id
+	JOIN users tu ON tu.id = fr.target_id`
+
+// scanFollowRequest legge una richiesta di follow selezionata con followRequestColumns
+func scanFollowRequest(row rowScanner) (FollowRequest, error) {
+	var request FollowRequest
+	err := row.Scan(&request.ID, &request.Requester.ID, &request.Requester.Username, &request.Target.ID, &request.Target.Username, &request.Timestamp)
+	return request, err
+}
+
+// IsPrivate indica se l'account userID è privato
+func (a *appdbimpl) IsPrivate(userID string) (bool, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return false, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	var private bool
+	err = a.c.QueryRow(`SELECT private FROM users WHERE id = ?`, UserID).Scan(&private)
+	if err != nil {
+		return false, fmt.Errorf("selecting private: %w", err)
+	}
+
+	return private, nil
+}
+
+// SetPrivate rende privato o pubblico l'account userID. Quando l'account torna pubblico le richieste di follow in
+// attesa vengono approvate.
+func (a *appdbimpl) SetPrivate(userID string, private bool) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	tx, err := a.c.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(`UPDATE users SET private = ? WHERE id = ?`, private, UserID)
+	if err != nil {
+		return fmt.Errorf("updating private: %w", err)
+	}
+
+	if !private {
+		_, err = tx.Exec(`INSERT INTO followers (follower_id, followed_id)
+			SELECT requester_id, target_id FROM follow_requests WHERE target_id = ?`, UserID)
+		if err != nil {
+			return fmt.Errorf("approving follow requests: %w", err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM follow_requests WHERE target_id = ?`, UserID)
+		if err != nil {
+			return fmt.Errorf("deleting follow requests: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing private: %w", err)
+	}
+
+	return nil
+}
+
+// SetFollowRequest registra la richiesta di follow di requesterID verso targetID; se esiste già non fa nulla
+func (a *appdbimpl) SetFollowRequest(requesterID string, targetID string, timestamp string) error {
+
+	RequesterID, err := strconv.Atoi(requesterID)
+	if err != nil {
+		return fmt.Errorf("converting requester ID to integer: %w", err)
+	}
+
+	TargetID, err := strconv.Atoi(targetID)
+	if err != nil {
+		return fmt.Errorf("converting target ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT OR IGNORE INTO follow_requests (requester_id, target_id, timestamp) VALUES (?, ?, ?)`,
+		RequesterID, TargetID, timestamp)
+	if err != nil {
+		return fmt.Errorf("inserting follow request: %w", err)
+	}
+
+	return nil
+}
+
+// HasFollowRequest indica se requesterID ha una richiesta di follow in attesa verso targetID
+func (a *appdbimpl) HasFollowRequest(requesterID string, targetID string) (bool, error) {
+
+	RequesterID, err := strconv.Atoi(requesterID)
+	if err != nil {
+		return false, fmt.Errorf("converting requester ID to integer: %w", err)
+	}
+
+	TargetID, err := strconv.Atoi(targetID)
+	if err != nil {
+		return false, fmt.Errorf("converting target ID to integer: %w", err)
+	}
+
+	var exists bool
+	err = a.c.QueryRow(`SELECT EXISTS (SELECT 1 FROM follow_requests WHERE requester_id = ? AND target_id = ?)`,
+		RequesterID, TargetID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("checking follow request: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetFollowRequestsByTarget restituisce le richieste di follow ricevute da targetID, dalla più recente
+func (a *appdbimpl) GetFollowRequestsByTarget(targetID string) ([]FollowRequest, error) {
+
+	TargetID, err := strconv.Atoi(targetID)
+	if err != nil {
+		return nil, fmt.Errorf("converting target ID to integer: %w", err)
+	}
+
+	return a.queryFollowRequests(`SELECT `+followRequestColumns+` FROM `+followRequestTables+`
+		WHERE fr.target_id = ? ORDER BY fr.id DESC`, TargetID)
+}
+
+// GetFollowRequestsByRequester restituisce le richieste di follow inviate da requesterID, dalla più recente
+func (a *appdbimpl) GetFollowRequestsByRequester(requesterID string) ([]FollowRequest, error) {
+
+	RequesterID, err := strconv.Atoi(requesterID)
+	if err != nil {
+		return nil, fmt.Errorf("converting requester ID to integer: %w", err)
+	}
+
+	return a.queryFollowRequests(`SELECT `+followRequestColumns+` FROM `+followRequestTables+`
+		WHERE fr.requester_id = ? ORDER BY fr.id DESC`, RequesterID)
+}
+
+// queryFollowRequests esegue una query che seleziona followRequestColumns
+func (a *appdbimpl) queryFollowRequests(query string, args ...interface{}) ([]FollowRequest, error) {
+	rows, err := a.c.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("selecting follow requests: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var requests []FollowRequest
+	for rows.Next() {
+		request, err := scanFollowRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning follow request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return requests, nil
+}
+
+// ApproveFollowRequest approva la richiesta di follow di requesterID verso targetID, che diventa un follow.
+// Restituisce sql.ErrNoRows se la richiesta non esiste.
+func (a *appdbimpl) ApproveFollowRequest(requesterID string, targetID string) error {
+
+	RequesterID, err := strconv.Atoi(requesterID)
+	if err != nil {
+		return fmt.Errorf("converting requester ID to integer: %w", err)
+	}
+
+	TargetID, err := strconv.Atoi(targetID)
+	if err != nil {
+		return fmt.Errorf("converting target ID to integer: %w", err)
+	}
+
+	tx, err := a.c.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`DELETE FROM follow_requests WHERE requester_id = ? AND target_id = ?`, RequesterID, TargetID)
+	if err != nil {
+		return fmt.Errorf("deleting follow request: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		err = sql.ErrNoRows
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO followers (follower_id, followed_id) VALUES (?, ?)`, RequesterID, TargetID)
+	if err != nil {
+		return fmt.Errorf("following user: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing follow request: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFollowRequest rifiuta la richiesta di follow di requesterID verso targetID. Restituisce sql.ErrNoRows se la
+// richiesta non esiste.
+func (a *appdbimpl) DeleteFollowRequest(requesterID string, targetID string) error {
+
+	RequesterID, err := strconv.Atoi(requesterID)
+	if err != nil {
+		return fmt.Errorf("converting requester ID to integer: %w", err)
+	}
+
+	TargetID, err := strconv.Atoi(targetID)
+	if err != nil {
+		return fmt.Errorf("converting target ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`DELETE FROM follow_requests WHERE requester_id = ? AND target_id = ?`, RequesterID, TargetID)
+	if err != nil {
+		return fmt.Errorf("deleting follow request: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
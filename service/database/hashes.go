@@ -0,0 +1,160 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// GetImageHashesByUserID restituisce gli hash percettivi delle immagini delle foto dell'utente userID, escluse quelle
+// nel cestino e le immagini senza hash
+func (a *appdbimpl) GetImageHashesByUserID(userID string) ([]ImageHash, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT pi.photo_id, pi.position, pi.phash FROM photo_images pi
+		JOIN photos ON photos.id = pi.photo_id
+		WHERE photos.user_id = ? AND photos.deleted_at IS NULL AND pi.phash IS NOT NULL AND pi.phash != ''
+		ORDER BY pi.photo_id, pi.position`, UserID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting image hashes: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var hashes []ImageHash
+	for rows.Next() {
+		var hash ImageHash
+		err = rows.Scan(&hash.PhotoID, &hash.Position, &hash.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("scanning image hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// GetUnhashedImages restituisce al più limit immagini di cui non è ancora stato calcolato l'hash percettivo, cioè
+// quelle caricate prima della sua introduzione
+func (a *appdbimpl) GetUnhashedImages(limit int) ([]PhotoImage, error) {
+	rows, err := a.c.Query(`SELECT photo_id, position, image_data FROM photo_images WHERE phash IS NULL
+		ORDER BY id LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("selecting unhashed images: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var images []PhotoImage
+	for rows.Next() {
+		var image PhotoImage
+		err = rows.Scan(&image.PhotoID, &image.Position, &image.ImageData)
+		if err != nil {
+			return nil, fmt.Errorf("scanning image: %w", err)
+		}
+		images = append(images, image)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return images, nil
+}
+
+// SetImageHash salva l'hash percettivo dell'immagine in posizione position della foto photoID; un hash vuoto indica
+// che l'immagine non può essere decodificata
+func (a *appdbimpl) SetImageHash(photoID int, position int, hash string) error {
+	_, err := a.c.Exec(`UPDATE photo_images SET phash = ? WHERE photo_id = ? AND position = ?`, hash, photoID, position)
+	if err != nil {
+		return fmt.Errorf("updating image hash: %w", err)
+	}
+
+	return nil
+}
+
+// GetBlockedHashes restituisce gli hash percettivi bloccati dagli amministratori, dall'ultimo aggiunto
+func (a *appdbimpl) GetBlockedHashes() ([]BlockedHash, error) {
+	rows, err := a.c.Query(`SELECT hash, reason, timestamp FROM blocked_hashes ORDER BY timestamp DESC, hash`)
+	if err != nil {
+		return nil, fmt.Errorf("selecting blocked hashes: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var blocked []BlockedHash
+	for rows.Next() {
+		var hash BlockedHash
+		err = rows.Scan(&hash.Hash, &hash.Reason, &hash.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("scanning blocked hash: %w", err)
+		}
+		blocked = append(blocked, hash)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return blocked, nil
+}
+
+// SetBlockedHash aggiunge un hash alla lista di quelli bloccati; se è già bloccato ne cambia solo il motivo
+func (a *appdbimpl) SetBlockedHash(blocked BlockedHash) error {
+	_, err := a.c.Exec(`INSERT INTO blocked_hashes (hash, reason, timestamp) VALUES (?, ?, ?)
+		ON CONFLICT (hash) DO UPDATE SET reason = excluded.reason`,
+		blocked.Hash, blocked.Reason, blocked.Timestamp)
+	if err != nil {
+		return fmt.Errorf("saving blocked hash: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBlockedHash toglie un hash dalla lista di quelli bloccati; restituisce sql.ErrNoRows se non era bloccato
+func (a *appdbimpl) DeleteBlockedHash(hash string) error {
+	result, err := a.c.Exec(`DELETE FROM blocked_hashes WHERE hash = ?`, hash)
+	if err != nil {
+		return fmt.Errorf("deleting blocked hash: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
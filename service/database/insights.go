@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// RecordPhotoView registra una visualizzazione della foto photoID da parte di viewerID al momento timestamp, a meno
+// che viewerID non l'abbia già vista dopo dedupSince (formato YYYYMMDDHHmmSS): così ogni utente conta al più una
+// visualizzazione per finestra di tempo
+func (a *appdbimpl) RecordPhotoView(photoID string, viewerID string, timestamp string, dedupSince string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT INTO photo_views (photo_id, viewer_id, timestamp) SELECT :photo, :viewer, :timestamp
+		WHERE NOT EXISTS (SELECT 1 FROM photo_views WHERE photo_id = :photo AND viewer_id = :viewer AND timestamp > :since)`,
+		sql.Named("photo", PhotoID), sql.Named("viewer", ViewerID), sql.Named("timestamp", timestamp),
+		sql.Named("since", dedupSince))
+	if err != nil {
+		return fmt.Errorf("inserting photo view: %w", err)
+	}
+
+	return nil
+}
+
+// GetPhotoInsights restituisce le statistiche della foto photoID, con le interazioni giornaliere da since (formato
+// YYYYMMDDHHmmSS)
+func (a *appdbimpl) GetPhotoInsights(photoID string, since string) (Insights, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return Insights{}, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	return a.getInsights(`photo_id = ?`, PhotoID, since)
+}
+
+// GetUserInsights restituisce le statistiche di tutte le foto dell'utente userID, escluse quelle nel cestino, con le
+// interazioni giornaliere da since (formato YYYYMMDDHHmmSS)
+func (a *appdbimpl) GetUserInsights(userID string, since string) (Insights, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return Insights{}, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	return a.getInsights(`photo_id IN (SELECT id FROM photos WHERE user_id = ? AND deleted_at IS NULL)`, UserID, since)
+}
+
+// getInsights calcola le statistiche delle foto che soddisfano la condizione photoFilter, su una colonna photo_id, il
+// cui unico parametro è arg
+func (a *appdbimpl) getInsights(photoFilter string, arg int, since string) (Insights, error) {
+	var insights Insights
+
+	err := a.c.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM photo_views WHERE `+photoFilter+`),
+		(SELECT COUNT(DISTINCT viewer_id) FROM photo_views WHERE `+photoFilter+`),
+		(SELECT COUNT(*) FROM likes WHERE reaction = ? AND `+photoFilter+`),
+		(SELECT COUNT(*) FROM comments WHERE deleted_at IS NULL AND `+photoFilter+`)`, arg, arg, DefaultReaction, arg, arg).
+		Scan(&insights.Views, &insights.UniqueViewers, &insights.Likes, &insights.Comments)
+	if err != nil {
+		return insights, fmt.Errorf("counting interactions: %w", err)
+	}
+
+	// Le interazioni di ogni tabella vengono raggruppate per giorno, cioè per le prime 8 cifre del timestamp; come
+	// altrove, tra le reazioni contano come like solo quelle di tipo DefaultReaction
+	rows, err := a.c.Query(`SELECT day, SUM(views), SUM(likes), SUM(comments) FROM (
+			SELECT substr(timestamp, 1, 8) AS day, 1 AS views, 0 AS likes, 0 AS comments
+			FROM photo_views WHERE `+photoFilter+` AND timestamp >= ?
+			UNION ALL
+			SELECT substr(timestamp, 1, 8), 0, 1, 0 FROM likes
+			WHERE reaction = ? AND `+photoFilter+` AND timestamp >= ?
+			UNION ALL
+			SELECT substr(timestamp, 1, 8), 0, 0, 1 FROM comments
+			WHERE deleted_at IS NULL AND `+photoFilter+` AND timestamp >= ?
+		) GROUP BY day ORDER BY day`, arg, since, DefaultReaction, arg, since, arg, since)
+	if err != nil {
+		return insights, fmt.Errorf("selecting daily interactions: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	for rows.Next() {
+		var day InsightsDay
+		err = rows.Scan(&day.Day, &day.Views, &day.Likes, &day.Comments)
+		if err != nil {
+			return insights, fmt.Errorf("scanning daily interactions: %w", err)
+		}
+		insights.Days = append(insights.Days, day)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return insights, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return insights, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// insertMentions salva le menzioni di una didascalia (photoID) o di un commento (commentID) all'interno della
+// transazione tx. Uno dei due ID deve essere nil.
+func insertMentions(tx *sql.Tx, photoID interface{}, commentID interface{}, mentions []Mention) error {
+	for _, mention := range mentions {
+		_, err := tx.Exec(`INSERT INTO mentions (user_id, photo_id, comment_id, text_offset, text_length) VALUES (?, ?, ?, ?, ?)`,
+			mention.UserID, photoID, commentID, mention.Offset, mention.Length)
+		if err != nil {
+			return fmt.Errorf("inserting mention: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getMentions restituisce le menzioni il cui campo column (photo_id o comment_id) è tra ids, raggruppate per ID.
+// Lo username viene letto da users, quindi riflette eventuali cambi di nome successivi alla menzione.
+func (a *appdbimpl) getMentions(column string, ids []int) (map[int][]Mention, error) {
+	mentions := make(map[int][]Mention)
+	if len(ids) == 0 {
+		return mentions, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	rows, err := a.c.Query(`SELECT m.`+column+`, m.user_id, u.username, m.text_offset, m.text_length
+		FROM mentions m JOIN users u ON u.id = m.user_id
+		WHERE m.`+column+` IN (`+placeholders+`)
+		ORDER BY m.text_offset`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("selecting mentions: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	for rows.Next() {
+		var ownerID int
+		var mention Mention
+		err = rows.Scan(&ownerID, &mention.UserID, &mention.Username, &mention.Offset, &mention.Length)
+		if err != nil {
+			return nil, fmt.Errorf("scanning mention: %w", err)
+		}
+		mentions[ownerID] = append(mentions[ownerID], mention)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return mentions, nil
+}
+
+// attachPhotoMentions valorizza il campo Mentions delle foto con le menzioni presenti nelle didascalie
+func (a *appdbimpl) attachPhotoMentions(photos []Photo) error {
+	ids := make([]int, len(photos))
+	for i, photo := range photos {
+		ids[i] = photo.ID
+	}
+
+	mentions, err := a.getMentions("photo_id", ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range photos {
+		photos[i].Mentions = mentions[photos[i].ID]
+	}
+
+	return nil
+}
+
+// attachCommentMentions valorizza il campo Mentions dei commenti con le menzioni presenti nel testo
+func (a *appdbimpl) attachCommentMentions(comments []Comment) error {
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	mentions, err := a.getMentions("comment_id", ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range comments {
+		comments[i].Mentions = mentions[comments[i].ID]
+	}
+
+	return nil
+}
@@ -5,11 +5,39 @@ type User struct {
 	Username string `json:"username"`
 }
 
+// Photo è un post: raggruppa una o più immagini in ordine (NumImages), likes e commenti appartengono al post.
+// ImageData è la prima immagine, le altre si scaricano singolarmente. PublishAt è valorizzato finché la foto è
+// programmata e non ancora pubblicata; in quel caso Timestamp è il momento della pubblicazione. DeletedAt è valorizzato
+// se la foto è nel cestino, ArchivedAt se la foto è archiviata. SavedByMe e LikedByMe indicano se l'utente che la
+// guarda l'ha salvata e se vi ha messo like. Repost è valorizzato solo nello stream, quando la foto vi compare
+// perché ricondivisa da un utente seguito.
 type Photo struct {
-	ID        int    `json:"id"`
-	UserID    int    `json:"user_id"`
-	ImageData []byte `json:"image_data"`
-	Timestamp string `json:"timestamp"`
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	ImageData     []byte    `json:"image_data"`
+	Timestamp     string    `json:"timestamp"`
+	Caption       string    `json:"caption"`
+	Mentions      []Mention `json:"mentions"`
+	NumImages     int       `json:"num_images"`
+	Visibility    string    `json:"visibility"`
+	CommentPolicy string    `json:"comment_policy"`
+	PublishAt     *string   `json:"publish_at,omitempty"`
+	DeletedAt     *string   `json:"deleted_at,omitempty"`
+	ArchivedAt    *string   `json:"archived_at,omitempty"`
+	SavedByMe     bool      `json:"saved_by_me"`
+	LikedByMe     bool      `json:"liked_by_me"`
+	Repost        *Repost   `json:"repost,omitempty"`
+}
+
+// PhotoDetails è una foto con le informazioni mostrate nella sua pagina: il nome del proprietario, i conteggi di like e
+// commenti, il numero di reazioni di ogni tipo e il numero di ricondivisioni
+type PhotoDetails struct {
+	Photo
+	OwnerUsername string         `json:"owner_username"`
+	NumLikes      int            `json:"num_likes"`
+	NumComments   int            `json:"num_comments"`
+	NumReposts    int            `json:"num_reposts"`
+	Reactions     map[string]int `json:"reactions"`
 }
 
 type Like struct {
@@ -18,22 +46,207 @@ type Like struct {
 	PhotoID int `json:"photo_id"`
 }
 
+// Reaction è la reazione di tipo Reaction messa a una foto dall'utente UserID, con il suo username attuale. I like
+// sono le reazioni di tipo DefaultReaction. FollowedByMe e FollowsMe indicano se l'utente che guarda la lista segue
+// l'utente UserID e se ne è seguito: sono entrambi veri per i follow reciproci.
+type Reaction struct {
+	ID           int    `json:"id"`
+	UserID       int    `json:"user_id"`
+	Username     string `json:"username"`
+	Reaction     string `json:"reaction"`
+	FollowedByMe bool   `json:"followed_by_me"`
+	FollowsMe    bool   `json:"follows_me"`
+}
+
+// Follow è un utente nella lista dei follower o dei seguiti di un altro utente, con il suo username attuale. ID è il
+// follow corrispondente, usato come cursore della lista. FollowedByMe, FollowsMe e FollowRequested indicano se l'utente
+// che guarda la lista segue l'utente UserID, se ne è seguito e se gli ha chiesto di seguirlo.
+type Follow struct {
+	ID              int    `json:"id"`
+	UserID          int    `json:"user_id"`
+	Username        string `json:"username"`
+	FollowedByMe    bool   `json:"followed_by_me"`
+	FollowsMe       bool   `json:"follows_me"`
+	FollowRequested bool   `json:"follow_requested"`
+}
+
+// Relationship è il rapporto dell'utente UserID con l'utente OtherID: Follows e FollowedBy indicano se lo segue e se
+// ne è seguito, Banned e BannedBy se lo ha bannato e se ne è stato bannato, FollowRequested e FollowRequestedBy se gli
+// ha chiesto di seguirlo e se ha ricevuto da lui una richiesta di follow ancora in attesa. Muted indica se lo ha
+// silenziato; chi è silenziato non lo sa, quindi non c'è il verso opposto.
+type Relationship struct {
+	UserID            int  `json:"user_id"`
+	OtherID           int  `json:"other_id"`
+	Follows           bool `json:"follows"`
+	FollowedBy        bool `json:"followed_by"`
+	Banned            bool `json:"banned"`
+	BannedBy          bool `json:"banned_by"`
+	FollowRequested   bool `json:"follow_requested"`
+	FollowRequestedBy bool `json:"follow_requested_by"`
+	Muted             bool `json:"muted"`
+}
+
+// Comment è un commento a una foto, con lo username attuale del suo autore. ParentID è il commento principale a cui
+// risponde, nil per i commenti principali, che hanno NumReplies risposte. Un commento principale eliminato quando aveva
+// già risposte resta come segnaposto, con DeletedAt impostato, senza testo, UserId pari a 0 e Username vuoto.
+// EditedAt è il momento dell'ultima modifica del testo, nil se il commento non è mai stato modificato. Un commento
+// nascosto (Hidden) dal proprietario della foto è visibile solo a lui e all'autore; i commenti fissati (Pinned) sono
+// mostrati per primi. Reactions è il numero di reazioni al commento per ogni tipo ricevuto e MyReaction la reazione
+// dell'utente che lo guarda, nil se non ne ha messe; come per le foto, NumLikes e LikedByMe riguardano solo le reazioni
+// di tipo DefaultReaction.
 type Comment struct {
-	ID        int    `json:"id"`
-	UserId    int    `json:"user_id"`
-	PhotoId   int    `json:"photo_id"`
+	ID         int            `json:"id"`
+	UserId     int            `json:"user_id"`
+	Username   string         `json:"username"`
+	PhotoId    int            `json:"photo_id"`
+	Text       string         `json:"text"`
+	Timestamp  string         `json:"timestamp"`
+	Mentions   []Mention      `json:"mentions"`
+	ParentID   *int           `json:"parent_id"`
+	NumReplies int            `json:"num_replies"`
+	DeletedAt  *string        `json:"deleted_at,omitempty"`
+	EditedAt   *string        `json:"edited_at,omitempty"`
+	Hidden     bool           `json:"hidden"`
+	Pinned     bool           `json:"pinned"`
+	NumLikes   int            `json:"num_likes"`
+	LikedByMe  bool           `json:"liked_by_me"`
+	Reactions  map[string]int `json:"reactions"`
+	MyReaction *string        `json:"my_reaction"`
+}
+
+// CommentVersion è una versione precedente del testo di un commento, scritta al momento Timestamp
+type CommentVersion struct {
 	Text      string `json:"text"`
 	Timestamp string `json:"timestamp"`
 }
 
+// Mention è una menzione @username dentro una didascalia o un commento. Offset e Length sono espressi in caratteri e
+// individuano la porzione di testo della menzione; Username è sempre quello attuale dell'utente menzionato.
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+// Bookmark è una foto salvata da un utente per rivederla in seguito; Timestamp è il momento del salvataggio
+type Bookmark struct {
+	ID        int    `json:"id"`
+	Photo     Photo  `json:"photo"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Repost è la ricondivisione della foto PhotoID da parte di User ai propri follower, con un commento facoltativo
+type Repost struct {
+	ID        int    `json:"id"`
+	User      User   `json:"user"`
+	PhotoID   int    `json:"photo_id"`
+	Comment   string `json:"comment"`
+	Timestamp string `json:"timestamp"`
+}
+
 type Follower struct {
 	ID         int `json:"id"`
 	FollowerID int `json:"follower_id"`
 	FollowedID int `json:"followed_id"`
 }
 
+// FollowRequest è una richiesta di follow di Requester verso l'account privato di Target, in attesa di approvazione
+type FollowRequest struct {
+	ID        int    `json:"id"`
+	Requester User   `json:"requester"`
+	Target    User   `json:"target"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Mute è il silenziamento di User da parte di un utente; Timestamp è il momento in cui è stato silenziato
+type Mute struct {
+	ID        int    `json:"id"`
+	User      User   `json:"user"`
+	Timestamp string `json:"timestamp"`
+}
+
 type Ban struct {
 	ID       int `json:"id"`
 	UserID   int `json:"user_id"`
 	BannedID int `json:"banned_id"`
 }
+
+// Album è una raccolta ordinata di foto di un utente. CoverPhotoID è la copertina scelta dall'utente oppure, se non
+// è stata scelta, la prima foto dell'album; è nil se l'album è vuoto e senza copertina.
+type Album struct {
+	ID           int     `json:"id"`
+	UserID       int     `json:"user_id"`
+	Title        string  `json:"title"`
+	CoverPhotoID *int    `json:"cover_photo_id"`
+	Timestamp    string  `json:"timestamp"`
+	NumPhotos    int     `json:"num_photos"`
+	Photos       []Photo `json:"photos,omitempty"`
+}
+
+// Upload è un upload ripristinabile: il client invia Length byte a blocchi, Offset indica quanti ne sono già arrivati.
+// Quando l'upload è completo diventa una foto e PhotoID la identifica.
+type Upload struct {
+	ID        string `json:"id"`
+	UserID    int    `json:"user_id"`
+	Length    int64  `json:"length"`
+	Offset    int64  `json:"offset"`
+	Metadata  string `json:"metadata"`
+	ExpiresAt string `json:"expires_at"`
+	PhotoID   *int   `json:"photo_id"`
+}
+
+// Insights sono le statistiche delle foto di un utente: i totali dall'inizio e, in Days, le interazioni ricevute giorno
+// per giorno. Days contiene solo i giorni con almeno un'interazione.
+type Insights struct {
+	Views         int           `json:"views"`
+	UniqueViewers int           `json:"unique_viewers"`
+	Likes         int           `json:"likes"`
+	Comments      int           `json:"comments"`
+	Days          []InsightsDay `json:"days"`
+}
+
+// InsightsDay sono le visualizzazioni, i like e i commenti ricevuti nel giorno Day (formato YYYYMMDD)
+type InsightsDay struct {
+	Day      string `json:"day"`
+	Views    int    `json:"views"`
+	Likes    int    `json:"likes"`
+	Comments int    `json:"comments"`
+}
+
+// StorageUsage è lo spazio occupato dalle foto di un utente, escluse quelle nel cestino: numero di post e byte delle
+// loro immagini
+type StorageUsage struct {
+	Photos int   `json:"photos"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// ImageHash è l'hash percettivo dell'immagine in posizione Position della foto PhotoID
+type ImageHash struct {
+	PhotoID  int
+	Position int
+	Hash     string
+}
+
+// PhotoImage è un'immagine della foto PhotoID, in posizione Position
+type PhotoImage struct {
+	PhotoID   int
+	Position  int
+	ImageData []byte
+}
+
+// BlockedHash è l'hash percettivo di un'immagine bloccata dagli amministratori: le immagini quasi identiche non
+// possono essere pubblicate
+type BlockedHash struct {
+	Hash      string `json:"hash"`
+	Reason    string `json:"reason"`
+	Timestamp string `json:"timestamp"`
+}
+
+// QuotaOverride sono i limiti di spazio impostati da un amministratore per un utente. Un limite nil non è stato
+// personalizzato e vale quello predefinito.
+type QuotaOverride struct {
+	UserID    int    `json:"user_id"`
+	MaxPhotos *int   `json:"max_photos"`
+	MaxBytes  *int64 `json:"max_bytes"`
+}
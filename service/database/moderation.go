@@ -0,0 +1,133 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Chi può commentare una foto, secondo la scelta del proprietario
+const (
+	// CommentsEveryone: chiunque veda la foto può commentarla
+	CommentsEveryone = "everyone"
+	// CommentsFollowers: solo il proprietario e i suoi follower possono commentare la foto
+	CommentsFollowers = "followers"
+	// CommentsOff: nessuno può commentare la foto; i commenti già scritti restano visibili
+	CommentsOff = "off"
+)
+
+// IsValidCommentPolicy indica se policy è una delle scelte su chi può commentare una foto
+func IsValidCommentPolicy(policy string) bool {
+	switch policy {
+	case CommentsEveryone, CommentsFollowers, CommentsOff:
+		return true
+	}
+	return false
+}
+
+// commentVisibleTo è la condizione SQL, su una riga di comments, vera se il commento è visibile all'utente indicato dal
+// parametro sql.Named("viewer", ...): i commenti nascosti sono visibili solo all'autore e al proprietario della foto
+const commentVisibleTo = `(comments.hidden_at IS NULL OR comments.user_id = :viewer
+	OR (SELECT cp.user_id FROM photos cp WHERE cp.id = comments.photo_id) = :viewer)`
+
+// commentsPinnedFirst è l'ordinamento dei commenti principali: prima quelli fissati, nell'ordine in cui sono stati
+// fissati, poi gli altri dal meno recente
+const commentsPinnedFirst = `comments.pinned_at IS NULL, comments.pinned_at, comments.id`
+
+// SetPhotoCommentPolicy cambia chi può commentare la foto photoID
+func (a *appdbimpl) SetPhotoCommentPolicy(photoID string, policy string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE photos SET comment_policy = ? WHERE id = ?`, policy, PhotoID)
+	if err != nil {
+		return fmt.Errorf("updating comment policy: %w", err)
+	}
+
+	return nil
+}
+
+// HideComment nasconde il commento commentID agli utenti diversi dall'autore e dal proprietario della foto, al
+// momento hiddenAt; un commento nascosto non resta fissato in alto. Nascondere un commento già nascosto non ha effetto.
+func (a *appdbimpl) HideComment(commentID string, hiddenAt string) error {
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE comments SET hidden_at = COALESCE(hidden_at, ?), pinned_at = NULL WHERE id = ?`,
+		hiddenAt, CommentID)
+	if err != nil {
+		return fmt.Errorf("hiding comment: %w", err)
+	}
+
+	return nil
+}
+
+// UnhideComment rende di nuovo visibile a tutti il commento commentID
+func (a *appdbimpl) UnhideComment(commentID string) error {
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE comments SET hidden_at = NULL WHERE id = ?`, CommentID)
+	if err != nil {
+		return fmt.Errorf("unhiding comment: %w", err)
+	}
+
+	return nil
+}
+
+// PinComment fissa in alto il commento commentID al momento pinnedAt. Fissare un commento già fissato non ha effetto.
+func (a *appdbimpl) PinComment(commentID string, pinnedAt string) error {
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE comments SET pinned_at = COALESCE(pinned_at, ?) WHERE id = ?`, pinnedAt, CommentID)
+	if err != nil {
+		return fmt.Errorf("pinning comment: %w", err)
+	}
+
+	return nil
+}
+
+// UnpinComment toglie il commento commentID da quelli fissati in alto
+func (a *appdbimpl) UnpinComment(commentID string) error {
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE comments SET pinned_at = NULL WHERE id = ?`, CommentID)
+	if err != nil {
+		return fmt.Errorf("unpinning comment: %w", err)
+	}
+
+	return nil
+}
+
+// CountPinnedComments restituisce il numero di commenti fissati in alto sulla foto photoID
+func (a *appdbimpl) CountPinnedComments(photoID string) (int, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return 0, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	var count int
+	err = a.c.QueryRow(`SELECT COUNT(*) FROM comments WHERE photo_id = ? AND pinned_at IS NOT NULL`, PhotoID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting pinned comments: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// MuteUser silenzia l'utente mutedID per l'utente userID: le sue foto spariscono dallo stream e i suoi commenti dalle
+// foto che userID guarda, senza che mutedID lo sappia; silenziarlo di nuovo non ha effetto
+func (a *appdbimpl) MuteUser(userID string, mutedID string, timestamp string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	MutedID, err := strconv.Atoi(mutedID)
+	if err != nil {
+		return fmt.Errorf("converting muted user ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT OR IGNORE INTO mutes (user_id, muted_id, timestamp) VALUES (?, ?, ?)`,
+		UserID, MutedID, timestamp)
+	if err != nil {
+		return fmt.Errorf("inserting mute: %w", err)
+	}
+
+	return nil
+}
+
+// UnmuteUser toglie il silenziamento dell'utente mutedID da parte dell'utente userID; restituisce sql.ErrNoRows se
+// l'utente non era silenziato
+func (a *appdbimpl) UnmuteUser(userID string, mutedID string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	MutedID, err := strconv.Atoi(mutedID)
+	if err != nil {
+		return fmt.Errorf("converting muted user ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`DELETE FROM mutes WHERE user_id = ? AND muted_id = ?`, UserID, MutedID)
+	if err != nil {
+		return fmt.Errorf("deleting mute: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetMutesByUserID restituisce al più limit utenti silenziati dall'utente userID, dall'ultimo silenziato, a partire
+// dal primo precedente al silenziamento before (0 per iniziare dall'ultimo)
+func (a *appdbimpl) GetMutesByUserID(userID string, before int, limit int) ([]Mute, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT mutes.id, users.id, users.username, mutes.timestamp
+		FROM mutes JOIN users ON users.id = mutes.muted_id
+		WHERE mutes.user_id = ? AND (? = 0 OR mutes.id < ?)
+		ORDER BY mutes.id DESC LIMIT ?`, UserID, before, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("selecting mutes: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var mutes []Mute
+	for rows.Next() {
+		var mute Mute
+		err = rows.Scan(&mute.ID, &mute.User.ID, &mute.User.Username, &mute.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("scanning mute: %w", err)
+		}
+		mutes = append(mutes, mute)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return mutes, nil
+}
@@ -4,14 +4,14 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"sort"
 	"strconv"
-	"time"
+	"strings"
 )
 
-/*SetPhoto salva la foto in locale e inserisce dati in photos (id, user_id, url, timestamp) */
+/*SetPhoto inserisce una nuova foto in photos (id, user_id, timestamp, caption, visibility) e le sue immagini in photo_images, in ordine,
+con l'hash percettivo hashes[i] di ogni immagine (vuoto se non calcolabile). Se scheduled è true la foto resta nascosta fino a timestamp, quando PublishDuePhotos la pubblica */
 
-func (a *appdbimpl) SetPhoto(userID string, image_data []byte, timestamp string) (int64, error) {
+func (a *appdbimpl) SetPhoto(userID string, images [][]byte, hashes []string, caption string, mentions []Mention, visibility string, timestamp string, scheduled bool) (int64, error) {
 
 	userId, err := strconv.Atoi(userID)
 	log.Printf("%d", userId)
@@ -19,7 +19,24 @@ func (a *appdbimpl) SetPhoto(userID string, image_data []byte, timestamp string)
 		return 0, fmt.Errorf("converting user ID to integer: %w", err)
 	}
 
-	result, err := a.c.Exec(`INSERT INTO photos (user_id, image_data, timestamp) VALUES (?, ?, ?)`, userId, image_data, timestamp)
+	// Foto, immagini e menzioni della didascalia vengono salvate insieme
+	tx, err := a.c.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var publishAt *string
+	if scheduled {
+		publishAt = &timestamp
+	}
+
+	result, err := tx.Exec(`INSERT INTO photos (user_id, timestamp, caption, visibility, publish_at) VALUES (?, ?, ?, ?, ?)`,
+		userId, timestamp, caption, visibility, publishAt)
 	log.Printf("%d,%s", userId, timestamp)
 	if err != nil {
 		return 0, fmt.Errorf("inserting photo: %w", err)
@@ -30,11 +47,50 @@ func (a *appdbimpl) SetPhoto(userID string, image_data []byte, timestamp string)
 		return 0, fmt.Errorf("getting last insert ID: %w", err)
 	}
 
+	for position, image := range images {
+		_, err = tx.Exec(`INSERT INTO photo_images (photo_id, position, image_data, phash) VALUES (?, ?, ?, ?)`,
+			id, position, image, hashes[position])
+		if err != nil {
+			return 0, fmt.Errorf("inserting photo image: %w", err)
+		}
+	}
+
+	err = insertMentions(tx, id, nil, mentions)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("committing photo: %w", err)
+	}
+
 	log.Printf("Inserted photo for user ID %d, timestamp: %s", userId, timestamp)
 
 	return id, nil
 }
 
+// photoColumns sono le colonne di photos lette da scanPhoto, nello stesso ordine. Come image_data viene letta la prima
+// immagine della foto.
+const photoColumns = `photos.id, photos.user_id,
+	(SELECT pi.image_data FROM photo_images pi WHERE pi.photo_id = photos.id ORDER BY pi.position LIMIT 1),
+	photos.timestamp, photos.caption,
+	(SELECT COUNT(*) FROM photo_images pi WHERE pi.photo_id = photos.id),
+	photos.visibility, photos.publish_at, photos.deleted_at, photos.archived_at, photos.comment_policy`
+
+// rowScanner è implementato sia da *sql.Row che da *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPhoto legge una foto selezionata con photoColumns; extra riceve le eventuali colonne selezionate dopo photoColumns
+func scanPhoto(row rowScanner, extra ...interface{}) (Photo, error) {
+	var photo Photo
+	dest := []interface{}{&photo.ID, &photo.UserID, &photo.ImageData, &photo.Timestamp, &photo.Caption, &photo.NumImages, &photo.Visibility, &photo.PublishAt, &photo.DeletedAt, &photo.ArchivedAt, &photo.CommentPolicy}
+	err := row.Scan(append(dest, extra...)...)
+	return photo, err
+}
+
 // GetPhotoByID restituisce i dettagli della foto in photos con photos_id=id
 func (a *appdbimpl) GetPhotoByID(photoID string) (Photo, error) {
 	var photo Photo
@@ -49,21 +105,89 @@ func (a *appdbimpl) GetPhotoByID(photoID string) (Photo, error) {
 	log.Printf("Fetching photo with ID: %d\n", PhotoID)
 
 	// Esegui la query per ottenere i dettagli della foto
-	err = a.c.QueryRow(`SELECT * FROM photos WHERE id = ?`, PhotoID).
-		Scan(&photo.ID, &photo.UserID, &photo.ImageData, &photo.Timestamp)
+	photo, err = scanPhoto(a.c.QueryRow(`SELECT `+photoColumns+` FROM photos WHERE id = ?`, PhotoID))
 	if err != nil {
 		// Log per gli errori durante il recupero della foto
 		log.Printf("Error fetching photo with ID %d: %v\n", PhotoID, err)
 		return photo, fmt.Errorf("selecting photo: %w", err)
 	}
 
+	mentions, err := a.getMentions("photo_id", []int{photo.ID})
+	if err != nil {
+		return photo, err
+	}
+	photo.Mentions = mentions[photo.ID]
+
 	// Log per indicare il successo nel recupero della foto
 	log.Printf("Photo with ID %d fetched successfully\n", PhotoID)
 
 	return photo, nil
 }
 
-// DeletePhoto elimina la foto con photos_id=id dalla tabella photos
+// GetPhotoDetails restituisce la foto photoID insieme al nome del proprietario, ai conteggi di like, reazioni e
+// commenti e all'indicazione se viewerID ha messo like e l'ha salvata
+func (a *appdbimpl) GetPhotoDetails(photoID string, viewerID string) (PhotoDetails, error) {
+	var details PhotoDetails
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return details, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return details, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	var savedByMe, likedByMe bool
+	details.Photo, err = scanPhoto(a.c.QueryRow(`SELECT `+photoColumns+`, users.username,
+		(SELECT COUNT(*) FROM likes WHERE likes.photo_id = photos.id AND likes.reaction = :like),
+		(SELECT COUNT(*) FROM comments WHERE comments.photo_id = photos.id AND comments.deleted_at IS NULL),
+		(SELECT COUNT(*) FROM reposts WHERE reposts.photo_id = photos.id),
+		EXISTS (SELECT 1 FROM likes WHERE likes.photo_id = photos.id AND likes.user_id = :viewer AND likes.reaction = :like),
+		EXISTS (SELECT 1 FROM bookmarks WHERE bookmarks.photo_id = photos.id AND bookmarks.user_id = :viewer)
+		FROM photos JOIN users ON users.id = photos.user_id WHERE photos.id = :photo`,
+		sql.Named("viewer", ViewerID), sql.Named("photo", PhotoID), sql.Named("like", DefaultReaction)),
+		&details.OwnerUsername, &details.NumLikes, &details.NumComments, &details.NumReposts, &likedByMe, &savedByMe)
+	if err != nil {
+		return details, fmt.Errorf("selecting photo details: %w", err)
+	}
+	details.SavedByMe = savedByMe
+	details.LikedByMe = likedByMe
+
+	mentions, err := a.getMentions("photo_id", []int{details.ID})
+	if err != nil {
+		return details, err
+	}
+	details.Mentions = mentions[details.ID]
+
+	details.Reactions, err = a.CountReactionsByPhotoID(photoID)
+	if err != nil {
+		return details, err
+	}
+
+	return details, nil
+}
+
+// GetPhotoImage restituisce l'immagine in posizione position (a partire da 0) della foto photoID
+func (a *appdbimpl) GetPhotoImage(photoID string, position int) ([]byte, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return nil, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	var image []byte
+	err = a.c.QueryRow(`SELECT image_data FROM photo_images WHERE photo_id = ? AND position = ?`, PhotoID, position).Scan(&image)
+	if err != nil {
+		return nil, fmt.Errorf("selecting photo image: %w", err)
+	}
+
+	return image, nil
+}
+
+// DeletePhoto elimina definitivamente la foto con photos_id=id dalla tabella photos, insieme a immagini, likes e commenti,
+// in un'unica transazione. Le foto eliminate dagli utenti passano prima dal cestino (TrashPhoto).
 func (a *appdbimpl) DeletePhoto(photoID string) error {
 
 	PhotoID, err := strconv.Atoi(photoID)
@@ -71,26 +195,93 @@ func (a *appdbimpl) DeletePhoto(photoID string) error {
 		return fmt.Errorf("converting photo ID to integer: %w", err)
 	}
 
-	_, err = a.c.Exec(`DELETE FROM photos WHERE id = ?`, PhotoID)
+	tx, err := a.c.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(`DELETE FROM photos WHERE id = ?`, PhotoID)
 	if err != nil {
 		return fmt.Errorf("deleting photo: %w", err)
 	}
 
-	_, err = a.c.Exec(`DELETE FROM comments WHERE photo_id = ?`, PhotoID)
+	_, err = tx.Exec(`DELETE FROM photo_images WHERE photo_id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting photo images: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM mentions WHERE photo_id = ? OR comment_id IN (SELECT id FROM comments WHERE photo_id = ?)`, PhotoID, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting mentions: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM comment_edits WHERE comment_id IN (SELECT id FROM comments WHERE photo_id = ?)`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting comment edits: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM comment_likes WHERE comment_id IN (SELECT id FROM comments WHERE photo_id = ?)`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting comment likes: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM comments WHERE photo_id = ?`, PhotoID)
 	if err != nil {
 		return fmt.Errorf("deleting comments: %w", err)
 	}
 
-	_, err = a.c.Exec(`DELETE FROM likes WHERE photo_id = ?`, PhotoID)
+	_, err = tx.Exec(`DELETE FROM likes WHERE photo_id = ?`, PhotoID)
 	if err != nil {
 		return fmt.Errorf("deleting likes: %w", err)
 	}
 
+	_, err = tx.Exec(`DELETE FROM bookmarks WHERE photo_id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting bookmarks: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM reposts WHERE photo_id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting reposts: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM photo_views WHERE photo_id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting views: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM album_photos WHERE photo_id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting album photos: %w", err)
+	}
+
+	_, err = tx.Exec(`UPDATE albums SET cover_photo_id = NULL WHERE cover_photo_id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("clearing album covers: %w", err)
+	}
+
+	// Gli upload completati che hanno creato la foto non hanno più un post da restituire
+	_, err = tx.Exec(`DELETE FROM uploads WHERE photo_id = ?`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting uploads: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
 	return nil
 }
 
-// SetComment inserisce un nuovo commento nel database nella tabella comment
-func (a *appdbimpl) SetComment(userID string, photoID string, comment string, timestamp string) (int64, error) {
+// SetComment inserisce un nuovo commento nel database nella tabella comment; se parentID non è nil il commento è una
+// risposta al commento principale parentID
+func (a *appdbimpl) SetComment(userID string, photoID string, parentID *int, comment string, mentions []Mention, timestamp string) (int64, error) {
 
 	UserID, err := strconv.Atoi(userID)
 	if err != nil {
@@ -102,7 +293,19 @@ func (a *appdbimpl) SetComment(userID string, photoID string, comment string, ti
 		return 0, fmt.Errorf("converting photo ID to integer: %w", err)
 	}
 
-	result, err := a.c.Exec(`INSERT INTO comments (user_id, photo_id, text, timestamp) VALUES (?, ?, ?, ?)`, UserID, PhotoID, comment, timestamp)
+	// Commento e menzioni vengono salvati insieme
+	tx, err := a.c.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	result, err := tx.Exec(`INSERT INTO comments (user_id, photo_id, parent_id, text, timestamp) VALUES (?, ?, ?, ?, ?)`,
+		UserID, PhotoID, parentID, comment, timestamp)
 	if err != nil {
 		return 0, fmt.Errorf("inserting comment: %w", err)
 	}
@@ -112,13 +315,62 @@ func (a *appdbimpl) SetComment(userID string, photoID string, comment string, ti
 		return 0, fmt.Errorf("getting last insert ID: %w", err)
 	}
 
+	err = insertMentions(tx, nil, id, mentions)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("committing comment: %w", err)
+	}
+
 	log.Printf("Inserted comment for photo:%d, user: %d at timestamp:%s", PhotoID, UserID, timestamp)
 
 	return id, nil
 }
 
-// GetCommentByID restituisce i dettagli del commento in comment con comment_id=id
-func (a *appdbimpl) GetCommentByID(commentID string) (Comment, error) {
+// commentColumns sono le colonne di commentsWithAuthor lette da scanComment, nello stesso ordine; la reazione
+// dell'utente e il numero di risposte sono calcolati per l'utente indicato dal parametro sql.Named("viewer", ...).
+// L'autore di un segnaposto non viene mostrato.
+var commentColumns = `comments.id, CASE WHEN comments.deleted_at IS NULL THEN comments.user_id ELSE 0 END,
+	COALESCE(author.username, ''), comments.photo_id, comments.text, comments.timestamp, comments.parent_id,
+	` + visibleRepliesCount + `, comments.deleted_at, comments.edited_at,
+	comments.hidden_at IS NOT NULL, comments.pinned_at IS NOT NULL,
+	(SELECT COUNT(*) FROM comment_likes cl WHERE cl.comment_id = comments.id AND cl.reaction = '` + DefaultReaction + `'),
+	(SELECT cl.reaction FROM comment_likes cl WHERE cl.comment_id = comments.id AND cl.user_id = :viewer)`
+
+// visibleRepliesCount è il numero di risposte a una riga di comments che GetRepliesByCommentID restituisce all'utente
+// indicato dal parametro sql.Named("viewer", ...): le condizioni sono le stesse, riscritte per la tabella replies
+var visibleRepliesCount = `(SELECT COUNT(*) FROM comments replies WHERE replies.parent_id = comments.id
+	AND replies.deleted_at IS NULL AND ` + strings.ReplaceAll(commentVisibleTo+` AND `+commentNotBanned+` AND `+
+	commentNotMuted, "comments.", "replies.") + `)`
+
+// commentsWithAuthor è la tabella da cui selezionare commentColumns: i commenti insieme al loro autore, se non sono
+// segnaposto
+const commentsWithAuthor = `comments LEFT JOIN users author ON author.id = comments.user_id AND comments.deleted_at IS NULL`
+
+// commentNotBanned è la condizione SQL, su una riga di comments, vera se né l'autore del commento ha bannato l'utente
+// indicato dal parametro sql.Named("viewer", ...), né questi ha bannato l'autore
+const commentNotBanned = `NOT EXISTS (SELECT 1 FROM bans WHERE (bans.user_id = comments.user_id AND bans.banned_id = :viewer)
+	OR (bans.user_id = :viewer AND bans.banned_id = comments.user_id))`
+
+// commentNotMuted è la condizione SQL, su una riga di comments, vera se l'autore del commento non è stato silenziato
+// dall'utente indicato dal parametro sql.Named("viewer", ...)
+const commentNotMuted = `NOT EXISTS (SELECT 1 FROM mutes WHERE mutes.user_id = :viewer AND mutes.muted_id = comments.user_id)`
+
+// scanComment legge un commento selezionato con commentColumns
+func scanComment(row rowScanner) (Comment, error) {
+	var comment Comment
+	err := row.Scan(&comment.ID, &comment.UserId, &comment.Username, &comment.PhotoId, &comment.Text,
+		&comment.Timestamp, &comment.ParentID, &comment.NumReplies, &comment.DeletedAt, &comment.EditedAt, &comment.Hidden,
+		&comment.Pinned, &comment.NumLikes, &comment.MyReaction)
+	comment.LikedByMe = comment.MyReaction != nil && *comment.MyReaction == DefaultReaction
+	return comment, err
+}
+
+// GetCommentByID restituisce i dettagli del commento in comment con comment_id=id, visti dall'utente viewerID
+func (a *appdbimpl) GetCommentByID(commentID string, viewerID string) (Comment, error) {
 
 	var comment Comment
 
@@ -127,38 +379,172 @@ func (a *appdbimpl) GetCommentByID(commentID string) (Comment, error) {
 		return comment, fmt.Errorf("converting comment ID to integer: %w", err)
 	}
 
-	err = a.c.QueryRow(`SELECT * FROM comments WHERE id = ?`, CommentID).Scan(&comment.ID, &comment.UserId, &comment.PhotoId, &comment.Text, &comment.Timestamp)
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return comment, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	comment, err = scanComment(a.c.QueryRow(`SELECT `+commentColumns+` FROM `+commentsWithAuthor+`
+		WHERE comments.id = :comment`, sql.Named("comment", CommentID), sql.Named("viewer", ViewerID)))
 	if err != nil {
 		return comment, fmt.Errorf("selecting comment: %w", err)
 	}
 
-	return comment, nil
+	mentions, err := a.getMentions("comment_id", []int{comment.ID})
+	if err != nil {
+		return comment, err
+	}
+	comment.Mentions = mentions[comment.ID]
+
+	comments := []Comment{comment}
+	err = a.attachCommentReactions(comments)
+	if err != nil {
+		return comment, err
+	}
+
+	return comments[0], nil
 }
 
-// DeleteComment elimina il commento con comment_id=id dalla tabella comment
-func (a *appdbimpl) DeleteComment(commentID string) error {
+// DeleteComment elimina il commento con comment_id=id dalla tabella comment. Un commento con risposte diventa invece
+// un segnaposto senza testo, eliminato al momento deletedAt, così le risposte non restano orfane; il segnaposto viene
+// eliminato insieme alla sua ultima risposta.
+func (a *appdbimpl) DeleteComment(commentID string, deletedAt string) error {
 
 	CommentID, err := strconv.Atoi(commentID)
 	if err != nil {
 		return fmt.Errorf("converting comment ID to integer: %w", err)
 	}
 
-	_, err = a.c.Exec(`DELETE FROM comments WHERE id = ?`, CommentID)
+	tx, err := a.c.Begin()
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var parentID sql.NullInt64
+	var replies int
+	err = tx.QueryRow(`SELECT parent_id, (SELECT COUNT(*) FROM comments r WHERE r.parent_id = comments.id)
+		FROM comments WHERE id = ?`, CommentID).Scan(&parentID, &replies)
+	if err != nil {
+		return fmt.Errorf("selecting comment: %w", err)
+	}
+
+	if replies > 0 {
+		_, err = tx.Exec(`UPDATE comments SET text = '', deleted_at = ?, edited_at = NULL, pinned_at = NULL WHERE id = ?`,
+			deletedAt, CommentID)
+		if err != nil {
+			return fmt.Errorf("replacing comment with a tombstone: %w", err)
+		}
+	} else {
+		_, err = tx.Exec(`DELETE FROM comments WHERE id = ?`, CommentID)
+		if err != nil {
+			return fmt.Errorf("deleting comment: %w", err)
+		}
+
+		// Il segnaposto del commento principale non serve più se questa era la sua ultima risposta
+		_, err = tx.Exec(`DELETE FROM comments WHERE id = ? AND deleted_at IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = comments.id)`, parentID)
+		if err != nil {
+			return fmt.Errorf("deleting tombstone: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM mentions WHERE comment_id = ?`, CommentID)
+	if err != nil {
+		return fmt.Errorf("deleting mentions: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM comment_likes WHERE comment_id = ?`, CommentID)
+	if err != nil {
+		return fmt.Errorf("deleting comment likes: %w", err)
+	}
+
+	// Le versioni precedenti vengono eliminate anche se del commento resta il segnaposto
+	_, err = tx.Exec(`DELETE FROM comment_edits WHERE comment_id = ?`, CommentID)
+	if err != nil {
+		return fmt.Errorf("deleting comment edits: %w", err)
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("deleting comment: %w", err)
+		return fmt.Errorf("committing comment deletion: %w", err)
 	}
 
 	return nil
 }
 
-// GetCommentsByPhotoID restituisce i dettagli dei commenti in comment con photos_id=id
-func (a *appdbimpl) GetCommentsByPhotoID(photoID string) ([]Comment, error) {
+// GetCommentsByPhotoID restituisce al più limit commenti principali non fissati in alto della foto photoID visibili
+// all'utente viewerID, con il numero di risposte di ognuno, a partire dal primo successivo al commento after (0 per
+// iniziare dal primo): dal più recente se newestFirst è true, altrimenti dal meno recente. I commenti di chi ha
+// bannato viewerID, o è stato bannato o silenziato da lui, non vengono restituiti.
+func (a *appdbimpl) GetCommentsByPhotoID(photoID string, viewerID string, newestFirst bool, after int, limit int) ([]Comment, error) {
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return nil, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	page := `(:after = 0 OR comments.id > :after) ORDER BY comments.id`
+	if newestFirst {
+		page = `(:after = 0 OR comments.id < :after) ORDER BY comments.id DESC`
+	}
+
+	return a.queryComments(`SELECT `+commentColumns+` FROM `+commentsWithAuthor+` WHERE comments.photo_id = :photo
+		AND comments.parent_id IS NULL AND comments.pinned_at IS NULL AND `+commentVisibleTo+`
+		AND `+commentNotBanned+` AND `+commentNotMuted+`
+		AND `+page+` LIMIT :limit`,
+		sql.Named("photo", PhotoID), sql.Named("viewer", ViewerID), sql.Named("after", after), sql.Named("limit", limit))
+}
+
+// GetPinnedCommentsByPhotoID restituisce i commenti fissati in alto sulla foto photoID visibili all'utente viewerID,
+// nell'ordine in cui sono stati fissati, escludendo come GetCommentsByPhotoID quelli degli utenti bannati o silenziati
+func (a *appdbimpl) GetPinnedCommentsByPhotoID(photoID string, viewerID string) ([]Comment, error) {
 	PhotoID, err := strconv.Atoi(photoID)
 	if err != nil {
 		return nil, fmt.Errorf("converting photo ID to integer: %w", err)
 	}
 
-	rows, err := a.c.Query(`SELECT * FROM comments WHERE photo_id = ?`, PhotoID)
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	return a.queryComments(`SELECT `+commentColumns+` FROM `+commentsWithAuthor+` WHERE comments.photo_id = :photo
+		AND comments.pinned_at IS NOT NULL AND `+commentVisibleTo+` AND `+commentNotBanned+` AND `+commentNotMuted+`
+		ORDER BY comments.pinned_at, comments.id`,
+		sql.Named("photo", PhotoID), sql.Named("viewer", ViewerID))
+}
+
+// GetFirstCommentsByPhotoID restituisce i primi limit commenti principali della foto visibili all'utente viewerID,
+// escludendo quelli degli utenti bannati o silenziati: prima quelli fissati in alto, poi gli altri dal meno recente
+func (a *appdbimpl) GetFirstCommentsByPhotoID(photoID string, viewerID string, limit int) ([]Comment, error) {
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return nil, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	return a.queryComments(`SELECT `+commentColumns+` FROM `+commentsWithAuthor+` WHERE comments.photo_id = :photo
+		AND comments.parent_id IS NULL AND `+commentVisibleTo+` AND `+commentNotBanned+` AND `+commentNotMuted+`
+		ORDER BY `+commentsPinnedFirst+` LIMIT :limit`,
+		sql.Named("photo", PhotoID), sql.Named("viewer", ViewerID), sql.Named("limit", limit))
+}
+
+// queryComments esegue una query che seleziona commentColumns e restituisce i commenti con le loro menzioni
+func (a *appdbimpl) queryComments(query string, args ...interface{}) ([]Comment, error) {
+	rows, err := a.c.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("selecting comments: %w", err)
 	}
@@ -172,8 +558,7 @@ func (a *appdbimpl) GetCommentsByPhotoID(photoID string) ([]Comment, error) {
 
 	var comments []Comment
 	for rows.Next() {
-		var comment Comment
-		err = rows.Scan(&comment.ID, &comment.UserId, &comment.PhotoId, &comment.Text, &comment.Timestamp)
+		comment, err := scanComment(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scanning comment: %w", err)
 		}
@@ -185,18 +570,34 @@ func (a *appdbimpl) GetCommentsByPhotoID(photoID string) ([]Comment, error) {
 		return nil, fmt.Errorf("iterating rows: %w", err)
 	}
 
+	err = a.attachCommentMentions(comments)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.attachCommentReactions(comments)
+	if err != nil {
+		return nil, err
+	}
+
 	return comments, nil
 }
 
-// GetPhotosByUserID restituisce i dettagli delle foto in photos con user_id=id
-func (a *appdbimpl) GetPhotosByUserID(userId string) ([]Photo, error) {
+// GetPhotosByUserID restituisce i dettagli delle foto in photos con user_id=id visibili all'utente viewerID
+func (a *appdbimpl) GetPhotosByUserID(userId string, viewerID string) ([]Photo, error) {
 
 	UserID, err := strconv.Atoi(userId)
 	if err != nil {
 		return nil, fmt.Errorf("converting user ID to integer: %w", err)
 	}
 
-	rows, err := a.c.Query(`SELECT * FROM photos WHERE user_id = ?`, UserID)
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT `+photoColumns+` FROM photos WHERE photos.user_id = :user AND `+photoPublished+`
+		AND `+photoVisibleTo+` ORDER BY photos.timestamp DESC, photos.id DESC`, sql.Named("user", UserID), sql.Named("viewer", ViewerID))
 	if err != nil {
 		return nil, fmt.Errorf("selecting photos: %w", err)
 	}
@@ -212,8 +613,7 @@ func (a *appdbimpl) GetPhotosByUserID(userId string) ([]Photo, error) {
 	var photos []Photo
 
 	for rows.Next() {
-		var photo Photo
-		err = rows.Scan(&photo.ID, &photo.UserID, &photo.ImageData, &photo.Timestamp)
+		photo, err := scanPhoto(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scanning photo: %w", err)
 		}
@@ -225,29 +625,21 @@ func (a *appdbimpl) GetPhotosByUserID(userId string) ([]Photo, error) {
 		return nil, fmt.Errorf("iterating rows: %w", err)
 	}
 
-	return photos, nil
-}
-
-// SetLike incrementa il numero di like di una foto
-func (a *appdbimpl) SetLike(userId string, photoID string) error {
-
-	UserID, err := strconv.Atoi(userId)
+	err = a.attachPhotoMentions(photos)
 	if err != nil {
-		return fmt.Errorf("converting user ID to integer: %w", err)
-
+		return nil, err
 	}
-
-	PhotoID, err := strconv.Atoi(photoID)
+	err = a.attachSavedByMe(photos, ViewerID)
 	if err != nil {
-		return fmt.Errorf("converting photo ID to integer: %w", err)
+		return nil, err
 	}
 
-	_, err = a.c.Exec(`INSERT INTO likes (user_id, photo_id) VALUES (?, ?)`, UserID, PhotoID)
+	err = a.attachLikedByMe(photos, ViewerID)
 	if err != nil {
-		return fmt.Errorf("inserting like: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return photos, nil
 }
 
 // DeleteLike decrementa il numero di like di una foto
@@ -266,7 +658,8 @@ func (a *appdbimpl) DeleteLike(likeID string) error {
 	return nil
 }
 
-// GetLikeByID restituisce i dettagli del like in likes con like_id=id
+// GetLikeByID restituisce i dettagli del like in likes con like_id=id; restituisce sql.ErrNoRows anche se likeID è
+// una reazione di tipo diverso da DefaultReaction
 func (a *appdbimpl) GetLikeByID(likeID string) (Like, error) {
 
 	var like Like
@@ -276,7 +669,8 @@ func (a *appdbimpl) GetLikeByID(likeID string) (Like, error) {
 		return like, fmt.Errorf("converting like ID to integer: %w", err)
 	}
 
-	err = a.c.QueryRow(`SELECT * FROM likes WHERE id = ?`, LikeID).Scan(&like.ID, &like.UserID, &like.PhotoID)
+	err = a.c.QueryRow(`SELECT id, user_id, photo_id FROM likes WHERE id = ? AND reaction = ?`, LikeID, DefaultReaction).
+		Scan(&like.ID, &like.UserID, &like.PhotoID)
 	if err != nil {
 		return like, fmt.Errorf("selecting like: %w", err)
 	}
@@ -284,7 +678,7 @@ func (a *appdbimpl) GetLikeByID(likeID string) (Like, error) {
 	return like, nil
 }
 
-// GetLikesByPhotoID restituisce il numero di like di una foto
+// GetLikesByPhotoID restituisce i like di una foto, cioè le reazioni di tipo DefaultReaction
 func (a *appdbimpl) GetLikesByPhotoID(photoID string) ([]Like, error) {
 
 	PhotoID, err := strconv.Atoi(photoID)
@@ -292,7 +686,8 @@ func (a *appdbimpl) GetLikesByPhotoID(photoID string) ([]Like, error) {
 		return nil, fmt.Errorf("converting photo ID to integer: %w", err)
 	}
 
-	rows, err := a.c.Query(`SELECT * FROM likes WHERE photo_id = ?`, PhotoID)
+	rows, err := a.c.Query(`SELECT id, user_id, photo_id FROM likes WHERE photo_id = ? AND reaction = ?`,
+		PhotoID, DefaultReaction)
 	if err != nil {
 		return nil, fmt.Errorf("selecting likes: %w", err)
 	}
@@ -324,57 +719,89 @@ func (a *appdbimpl) GetLikesByPhotoID(photoID string) ([]Like, error) {
 	return likes, nil
 }
 
-// GetPhotosStreamByUserID restituisce lista foto in ordine cronologico inverso di tutti account seguiti da userID
+// GetPhotosStreamByUserID restituisce lista foto in ordine cronologico inverso di tutti account seguiti da userID,
+// escluse quelle che userID non può vedere, insieme alle foto ricondivise dagli account seguiti, ordinate per momento
+// della ricondivisione. Le ricondivisioni sono escluse se userID segue già l'autore della foto o se l'autore lo ha
+// bannato. Le foto e le ricondivisioni degli utenti silenziati da userID sono escluse.
 func (a *appdbimpl) GetPhotosStreamByUserID(userID string) ([]Photo, error) {
 
-	follows, err := a.GetFollows(userID)
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
 
+	rows, err := a.c.Query(`SELECT `+photoColumns+`, NULL, NULL, NULL, NULL, NULL, photos.timestamp AS stream_time
+		FROM photos
+		JOIN followers ON followers.followed_id = photos.user_id AND followers.follower_id = :viewer
+		WHERE `+photoVisibleTo+` AND NOT EXISTS (SELECT 1 FROM mutes WHERE mutes.user_id = :viewer AND mutes.muted_id = photos.user_id)
+		UNION ALL
+		SELECT `+photoColumns+`, reposts.id, users.id, users.username, reposts.comment, reposts.timestamp, reposts.timestamp
+		FROM reposts
+		JOIN followers ON followers.followed_id = reposts.user_id AND followers.follower_id = :viewer
+		JOIN users ON users.id = reposts.user_id
+		JOIN photos ON photos.id = reposts.photo_id
+		WHERE `+photoVisibleTo+` AND photos.user_id <> :viewer
+		AND NOT EXISTS (SELECT 1 FROM followers af WHERE af.followed_id = photos.user_id AND af.follower_id = :viewer)
+		AND NOT EXISTS (SELECT 1 FROM bans WHERE bans.user_id = photos.user_id AND bans.banned_id = :viewer)
+		AND NOT EXISTS (SELECT 1 FROM mutes WHERE mutes.user_id = :viewer AND mutes.muted_id IN (photos.user_id, reposts.user_id))
+		ORDER BY stream_time DESC, 1 DESC`, sql.Named("viewer", UserID))
 	if err != nil {
-		return nil, fmt.Errorf("getting follows: %w", err)
+		return nil, fmt.Errorf("selecting photos: %w", err)
 	}
 
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
 	var photos []Photo
-	for _, follow := range follows {
-		rows, err := a.c.Query(`SELECT * FROM photos WHERE user_id = ?`, follow.ID)
+	for rows.Next() {
+		var repostID, reposterID sql.NullInt64
+		var reposterUsername, repostComment, repostTimestamp sql.NullString
+		var streamTime string
+		photo, err := scanPhoto(rows, &repostID, &reposterID, &reposterUsername, &repostComment, &repostTimestamp, &streamTime)
 		if err != nil {
-			return nil, fmt.Errorf("selecting photos: %w", err)
+			return nil, fmt.Errorf("scanning photo: %w", err)
 		}
 
-		for rows.Next() {
-			var photo Photo
-			err = rows.Scan(&photo.ID, &photo.UserID, &photo.ImageData, &photo.Timestamp)
-			if err != nil {
-				return nil, fmt.Errorf("scanning photo: %w", err)
+		if repostID.Valid {
+			photo.Repost = &Repost{
+				ID:        int(repostID.Int64),
+				User:      User{ID: int(reposterID.Int64), Username: reposterUsername.String},
+				PhotoID:   photo.ID,
+				Comment:   repostComment.String,
+				Timestamp: repostTimestamp.String,
 			}
-
-			photos = append(photos, photo)
-		}
-		if err = rows.Err(); err != nil {
-			err := rows.Close()
-			if err != nil {
-				log.Println("Error closing rows:", err)
-				return nil, err
-			} // Close rows before returning
-			return nil, fmt.Errorf("iterating rows: %w", err)
-		}
-		// Close rows after iterating
-		err = rows.Close()
-		if err != nil {
-			log.Println("Error closing rows:", err)
 		}
 
-		sort.Slice(photos, func(i, j int) bool {
-			t1, _ := time.Parse(time.RFC3339, photos[i].Timestamp)
-			t2, _ := time.Parse(time.RFC3339, photos[j].Timestamp)
-			return t2.Before(t1)
-		})
+		photos = append(photos, photo)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	err = a.attachPhotoMentions(photos)
+	if err != nil {
+		return nil, err
+	}
+	err = a.attachSavedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
 
+	err = a.attachLikedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
 	}
 
 	return photos, nil
 }
 
-// CountLikesByPhotoID restituisce il numero di like di una foto
+// CountLikesByPhotoID restituisce il numero di like di una foto, cioè di reazioni di tipo DefaultReaction
 func (a *appdbimpl) CountLikesByPhotoID(photoID string) (int, error) {
 
 	PhotoID, err := strconv.Atoi(photoID)
@@ -382,7 +809,7 @@ func (a *appdbimpl) CountLikesByPhotoID(photoID string) (int, error) {
 		return 0, fmt.Errorf("converting photo ID to integer: %w", err)
 	}
 
-	rows, err := a.c.Query(`SELECT COUNT(*) FROM likes WHERE photo_id = ?`, PhotoID)
+	rows, err := a.c.Query(`SELECT COUNT(*) FROM likes WHERE photo_id = ? AND reaction = ?`, PhotoID, DefaultReaction)
 	if err != nil {
 		return 0, fmt.Errorf("selecting likes: %w", err)
 	}
@@ -411,7 +838,7 @@ func (a *appdbimpl) CountLikesByPhotoID(photoID string) (int, error) {
 	return count, nil
 }
 
-// CountCommentsByPhotoID restituisce il numero di commenti di una foto
+// CountCommentsByPhotoID restituisce il numero di commenti di una foto, risposte comprese e segnaposti esclusi
 
 func (a *appdbimpl) CountCommentsByPhotoID(photoID string) (int, error) {
 
@@ -420,7 +847,7 @@ func (a *appdbimpl) CountCommentsByPhotoID(photoID string) (int, error) {
 		return 0, fmt.Errorf("converting photo ID to integer: %w", err)
 	}
 
-	rows, err := a.c.Query(`SELECT COUNT(*) FROM comments WHERE photo_id = ?`, PhotoID)
+	rows, err := a.c.Query(`SELECT COUNT(*) FROM comments WHERE photo_id = ? AND deleted_at IS NULL`, PhotoID)
 	if err != nil {
 		return 0, fmt.Errorf("selecting comments: %w", err)
 	}
@@ -451,14 +878,20 @@ func (a *appdbimpl) CountCommentsByPhotoID(photoID string) (int, error) {
 
 // CountPhotosByUserID restituisce il numero di foto di un utente
 
-func (a *appdbimpl) CountPhotosByUserID(userID string) (int, error) {
+func (a *appdbimpl) CountPhotosByUserID(userID string, viewerID string) (int, error) {
 
 	UserID, err := strconv.Atoi(userID)
 	if err != nil {
 		return 0, fmt.Errorf("converting user ID to integer: %w", err)
 	}
 
-	rows, err := a.c.Query(`SELECT COUNT(*) FROM photos WHERE user_id = ?`, UserID)
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return 0, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT COUNT(*) FROM photos WHERE photos.user_id = :user AND `+photoPublished+` AND `+photoVisibleTo,
+		sql.Named("user", UserID), sql.Named("viewer", ViewerID))
 	if err != nil {
 		return 0, fmt.Errorf("selecting photos: %w", err)
 	}
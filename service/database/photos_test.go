@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// mustSucceed interrompe il test se la preparazione what non è riuscita
+func mustSucceed(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+// countRows restituisce il numero di righe contate dalla query
+func countRows(t *testing.T, db *appdbimpl, query string, args ...interface{}) int {
+	t.Helper()
+
+	var count int
+	err := db.c.QueryRow(query, args...).Scan(&count)
+	mustSucceed(t, "counting rows", err)
+	return count
+}
+
+func TestDeletePhotoCascade(t *testing.T) {
+	db := newTestDB(t)
+	owner := newTestUser(t, db, "owner")
+	other := newTestUser(t, db, "other")
+	otherID, _ := strconv.Atoi(other)
+	mention := []Mention{{UserID: otherID, Username: "other", Offset: 0, Length: 6}}
+
+	// La foto eliminata ha almeno una riga in ogni tabella che la riguarda
+	id, err := db.SetPhoto(owner, [][]byte{[]byte("a"), []byte("b")}, []string{"", ""}, "@other", mention,
+		VisibilityPublic, testTimestamp, false)
+	mustSucceed(t, "creating photo", err)
+	photo := strconv.FormatInt(id, 10)
+
+	commentID, err := db.SetComment(other, photo, nil, "@other ciao", mention, testTimestamp)
+	mustSucceed(t, "commenting", err)
+	comment := strconv.FormatInt(commentID, 10)
+	parentID := int(commentID)
+	_, err = db.SetComment(owner, photo, &parentID, "grazie", nil, testTimestamp)
+	mustSucceed(t, "replying", err)
+
+	mustSucceed(t, "editing comment", db.EditComment(comment, "@other ciao!", mention, testTimestamp))
+	mustSucceed(t, "reacting to comment", db.SetCommentReaction(owner, comment, DefaultReaction, testTimestamp))
+	mustSucceed(t, "liking", db.SetReaction(other, photo, DefaultReaction, testTimestamp))
+	mustSucceed(t, "bookmarking", db.SetBookmark(other, photo, testTimestamp))
+	mustSucceed(t, "reposting", db.SetRepost(other, photo, "", testTimestamp))
+	mustSucceed(t, "viewing", db.RecordPhotoView(photo, other, testTimestamp, testTimestamp))
+	mustSucceed(t, "uploading", db.SetUpload(Upload{ID: "upload", UserID: otherID, Length: 1, ExpiresAt: testTimestamp}))
+	mustSucceed(t, "finishing upload", db.SetUploadPhoto("upload", id))
+
+	cover := int(id)
+	albumID, err := db.SetAlbum(owner, "album", &cover, testTimestamp)
+	mustSucceed(t, "creating album", err)
+	album := strconv.FormatInt(albumID, 10)
+	mustSucceed(t, "adding to album", db.AddPhotoToAlbum(album, photo))
+
+	// Le righe di un'altra foto non vengono toccate
+	kept := newTestPhoto(t, db, owner, VisibilityPublic, false)
+	_, err = db.SetComment(other, kept, nil, "bella", nil, testTimestamp)
+	mustSucceed(t, "commenting the other photo", err)
+	mustSucceed(t, "liking the other photo", db.SetReaction(other, kept, DefaultReaction, testTimestamp))
+
+	err = db.DeletePhoto(photo)
+	if err != nil {
+		t.Fatalf("DeletePhoto: %v", err)
+	}
+
+	tests := []struct {
+		table string
+		query string
+		arg   interface{}
+	}{
+		{"photos", `SELECT COUNT(*) FROM photos WHERE id = ?`, id},
+		{"photo_images", `SELECT COUNT(*) FROM photo_images WHERE photo_id = ?`, id},
+		{"caption mentions", `SELECT COUNT(*) FROM mentions WHERE photo_id = ?`, id},
+		{"comment mentions", `SELECT COUNT(*) FROM mentions WHERE comment_id = ?`, commentID},
+		{"comments", `SELECT COUNT(*) FROM comments WHERE photo_id = ?`, id},
+		{"comment_edits", `SELECT COUNT(*) FROM comment_edits WHERE comment_id = ?`, commentID},
+		{"comment_likes", `SELECT COUNT(*) FROM comment_likes WHERE comment_id = ?`, commentID},
+		{"likes", `SELECT COUNT(*) FROM likes WHERE photo_id = ?`, id},
+		{"bookmarks", `SELECT COUNT(*) FROM bookmarks WHERE photo_id = ?`, id},
+		{"reposts", `SELECT COUNT(*) FROM reposts WHERE photo_id = ?`, id},
+		{"photo_views", `SELECT COUNT(*) FROM photo_views WHERE photo_id = ?`, id},
+		{"album_photos", `SELECT COUNT(*) FROM album_photos WHERE photo_id = ?`, id},
+		{"album covers", `SELECT COUNT(*) FROM albums WHERE cover_photo_id = ?`, id},
+		{"uploads", `SELECT COUNT(*) FROM uploads WHERE photo_id = ?`, id},
+	}
+	for _, tt := range tests {
+		if count := countRows(t, db, tt.query, tt.arg); count != 0 {
+			t.Errorf("%s: %d rows left for the deleted photo", tt.table, count)
+		}
+	}
+
+	if count := countRows(t, db, `SELECT COUNT(*) FROM albums WHERE id = ?`, albumID); count != 1 {
+		t.Errorf("album: got %d rows, want 1", count)
+	}
+	if count := countRows(t, db, `SELECT COUNT(*) FROM comments WHERE photo_id = ?`, kept); count != 1 {
+		t.Errorf("other photo: got %d comments, want 1", count)
+	}
+	if count := countRows(t, db, `SELECT COUNT(*) FROM likes WHERE photo_id = ?`, kept); count != 1 {
+		t.Errorf("other photo: got %d likes, want 1", count)
+	}
+
+	_, err = db.GetUploadByID("upload")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("upload of the deleted photo: got error %v, want sql.ErrNoRows", err)
+	}
+}
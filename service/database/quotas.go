@@ -0,0 +1,93 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GetStorageUsage calcola lo spazio occupato dalle foto dell'utente userID. L'uso è sempre ricavato dalle foto
+// salvate, quindi resta esatto dopo ogni upload e ogni eliminazione. Le foto nel cestino non contano: chi elimina una
+// foto libera subito il suo spazio, che va ritrovato per ripristinarla.
+func (a *appdbimpl) GetStorageUsage(userID string) (StorageUsage, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return StorageUsage{}, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	var usage StorageUsage
+	err = a.c.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM photos WHERE user_id = ? AND deleted_at IS NULL),
+		(SELECT COALESCE(SUM(LENGTH(pi.image_data)), 0) FROM photo_images pi JOIN photos p ON p.id = pi.photo_id
+			WHERE p.user_id = ? AND p.deleted_at IS NULL)`,
+		UserID, UserID).Scan(&usage.Photos, &usage.Bytes)
+	if err != nil {
+		return usage, fmt.Errorf("selecting storage usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// GetPhotoSize restituisce i byte occupati dalle immagini della foto photoID
+func (a *appdbimpl) GetPhotoSize(photoID string) (int64, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return 0, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	var size int64
+	err = a.c.QueryRow(`SELECT COALESCE(SUM(LENGTH(image_data)), 0) FROM photo_images WHERE photo_id = ?`, PhotoID).
+		Scan(&size)
+	if err != nil {
+		return 0, fmt.Errorf("selecting photo size: %w", err)
+	}
+
+	return size, nil
+}
+
+// GetQuotaOverride restituisce i limiti personalizzati dell'utente userID, sql.ErrNoRows se non ne ha
+func (a *appdbimpl) GetQuotaOverride(userID string) (QuotaOverride, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return QuotaOverride{}, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	var override QuotaOverride
+	err = a.c.QueryRow(`SELECT user_id, max_photos, max_bytes FROM user_quotas WHERE user_id = ?`, UserID).
+		Scan(&override.UserID, &override.MaxPhotos, &override.MaxBytes)
+	if err != nil {
+		return override, fmt.Errorf("selecting quota override: %w", err)
+	}
+
+	return override, nil
+}
+
+// SetQuotaOverride salva i limiti personalizzati di un utente, sostituendo quelli precedenti
+func (a *appdbimpl) SetQuotaOverride(override QuotaOverride) error {
+	_, err := a.c.Exec(`INSERT INTO user_quotas (user_id, max_photos, max_bytes) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET max_photos = excluded.max_photos, max_bytes = excluded.max_bytes`,
+		override.UserID, override.MaxPhotos, override.MaxBytes)
+	if err != nil {
+		return fmt.Errorf("saving quota override: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteQuotaOverride riporta l'utente userID ai limiti predefiniti
+func (a *appdbimpl) DeleteQuotaOverride(userID string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`DELETE FROM user_quotas WHERE user_id = ?`, UserID)
+	if err != nil {
+		return fmt.Errorf("deleting quota override: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// DefaultReaction è il tipo di reazione dei like: le reazioni messe e tolte con le API dei like sono di questo tipo
+const DefaultReaction = "like"
+
+// SetReaction imposta la reazione dell'utente userID alla foto photoID, sostituendo quella di tipo diverso che aveva
+// eventualmente già messo; mettere di nuovo la stessa reazione non ha effetto
+func (a *appdbimpl) SetReaction(userID string, photoID string, reaction string, timestamp string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT INTO likes (user_id, photo_id, reaction, timestamp) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, photo_id) DO UPDATE SET reaction = excluded.reaction, timestamp = excluded.timestamp
+		WHERE likes.reaction != excluded.reaction`, UserID, PhotoID, reaction, timestamp)
+	if err != nil {
+		return fmt.Errorf("setting reaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReaction toglie la reazione dell'utente userID alla foto photoID; restituisce sql.ErrNoRows se l'utente non
+// aveva messo una reazione di tipo reaction
+func (a *appdbimpl) DeleteReaction(userID string, photoID string, reaction string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`DELETE FROM likes WHERE user_id = ? AND photo_id = ? AND reaction = ?`,
+		UserID, PhotoID, reaction)
+	if err != nil {
+		return fmt.Errorf("deleting reaction: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CountReactionsByPhotoID restituisce il numero di reazioni alla foto photoID per ogni tipo di reazione ricevuto
+func (a *appdbimpl) CountReactionsByPhotoID(photoID string) (map[string]int, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return nil, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT reaction, COUNT(*) FROM likes WHERE photo_id = ? GROUP BY reaction`, PhotoID)
+	if err != nil {
+		return nil, fmt.Errorf("counting reactions: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var reaction string
+		var count int
+		err = rows.Scan(&reaction, &count)
+		if err != nil {
+			return nil, fmt.Errorf("scanning reaction count: %w", err)
+		}
+		counts[reaction] = count
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetReactionsByPhotoID restituisce al più limit reazioni di tipo reaction alla foto photoID, dalla più recente, a
+// partire dalla prima precedente alla reazione after (0 per iniziare dalla più recente), con i rapporti di follow tra
+// chi ha reagito e l'utente viewerID
+func (a *appdbimpl) GetReactionsByPhotoID(photoID string, viewerID string, reaction string, after int, limit int) ([]Reaction, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return nil, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT likes.id, likes.user_id, users.username, likes.reaction,
+		EXISTS (SELECT 1 FROM followers f WHERE f.follower_id = :viewer AND f.followed_id = likes.user_id),
+		EXISTS (SELECT 1 FROM followers f WHERE f.follower_id = likes.user_id AND f.followed_id = :viewer)
+		FROM likes JOIN users ON users.id = likes.user_id
+		WHERE likes.photo_id = :photo AND likes.reaction = :reaction AND (:after = 0 OR likes.id < :after)
+		ORDER BY likes.id DESC LIMIT :limit`,
+		sql.Named("photo", PhotoID), sql.Named("viewer", ViewerID), sql.Named("reaction", reaction),
+		sql.Named("after", after), sql.Named("limit", limit))
+	if err != nil {
+		return nil, fmt.Errorf("selecting reactions: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var reactions []Reaction
+	for rows.Next() {
+		var r Reaction
+		err = rows.Scan(&r.ID, &r.UserID, &r.Username, &r.Reaction, &r.FollowedByMe, &r.FollowsMe)
+		if err != nil {
+			return nil, fmt.Errorf("scanning reaction: %w", err)
+		}
+		reactions = append(reactions, r)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return reactions, nil
+}
+
+// attachLikedByMe valorizza il campo LikedByMe delle foto, vero per quelle a cui l'utente viewerID ha messo like
+func (a *appdbimpl) attachLikedByMe(photos []Photo, viewerID int) error {
+	if len(photos) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(photos)+2)
+	args = append(args, viewerID, DefaultReaction)
+	for _, photo := range photos {
+		args = append(args, photo.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(photos)), ",")
+
+	rows, err := a.c.Query(`SELECT photo_id FROM likes WHERE user_id = ? AND reaction = ? AND photo_id IN (`+
+		placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("selecting likes: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	liked := make(map[int]bool)
+	for rows.Next() {
+		var photoID int
+		err = rows.Scan(&photoID)
+		if err != nil {
+			return fmt.Errorf("scanning like: %w", err)
+		}
+		liked[photoID] = true
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("iterating rows: %w", err)
+	}
+
+	for i := range photos {
+		photos[i].LikedByMe = liked[photos[i].ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// GetRepliesByCommentID restituisce al più limit risposte al commento commentID visibili all'utente viewerID, dalla
+// meno recente, a partire dalla prima successiva alla risposta after (0 per iniziare dalla prima). Le risposte degli
+// utenti bannati o silenziati sono escluse come in GetCommentsByPhotoID.
+func (a *appdbimpl) GetRepliesByCommentID(commentID string, viewerID string, after int, limit int) ([]Comment, error) {
+
+	CommentID, err := strconv.Atoi(commentID)
+	if err != nil {
+		return nil, fmt.Errorf("converting comment ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	return a.queryComments(`SELECT `+commentColumns+` FROM `+commentsWithAuthor+` WHERE comments.parent_id = :parent
+		AND comments.id > :after AND `+commentVisibleTo+` AND `+commentNotBanned+` AND `+commentNotMuted+`
+		ORDER BY comments.id LIMIT :limit`,
+		sql.Named("parent", CommentID), sql.Named("viewer", ViewerID), sql.Named("after", after), sql.Named("limit", limit))
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// SetRepost ricondivide la foto photoID ai follower dell'utente userID con il commento comment; se la foto è già
+// ricondivisa ne cambia solo il commento
+func (a *appdbimpl) SetRepost(userID string, photoID string, comment string, timestamp string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`INSERT INTO reposts (user_id, photo_id, comment, timestamp) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, photo_id) DO UPDATE SET comment = excluded.comment`,
+		UserID, PhotoID, comment, timestamp)
+	if err != nil {
+		return fmt.Errorf("saving repost: %w", err)
+	}
+
+	return nil
+}
+
+// GetRepost restituisce la ricondivisione della foto photoID da parte dell'utente userID
+func (a *appdbimpl) GetRepost(userID string, photoID string) (Repost, error) {
+	var repost Repost
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return repost, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return repost, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	err = a.c.QueryRow(`SELECT r.id, u.id, u.username, r.photo_id, r.comment, r.timestamp
+		FROM reposts r JOIN users u ON u.id = r.user_id WHERE r.user_id = ? AND r.photo_id = ?`, UserID, PhotoID).
+		Scan(&repost.ID, &repost.User.ID, &repost.User.Username, &repost.PhotoID, &repost.Comment, &repost.Timestamp)
+	if err != nil {
+		return repost, fmt.Errorf("selecting repost: %w", err)
+	}
+
+	return repost, nil
+}
+
+// DeleteRepost annulla la ricondivisione della foto photoID da parte dell'utente userID; restituisce sql.ErrNoRows se
+// la foto non era ricondivisa
+func (a *appdbimpl) DeleteRepost(userID string, photoID string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`DELETE FROM reposts WHERE user_id = ? AND photo_id = ?`, UserID, PhotoID)
+	if err != nil {
+		return fmt.Errorf("deleting repost: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CountRepostsByPhotoID restituisce il numero di ricondivisioni di una foto
+func (a *appdbimpl) CountRepostsByPhotoID(photoID string) (int, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return 0, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	var count int
+	err = a.c.QueryRow(`SELECT COUNT(*) FROM reposts WHERE photo_id = ?`, PhotoID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting reposts: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// GetScheduledPhotosByUserID restituisce le foto programmate e non ancora pubblicate dell'utente userID, dalla prossima
+// da pubblicare; le foto nel cestino sono escluse
+func (a *appdbimpl) GetScheduledPhotosByUserID(userID string) ([]Photo, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT `+photoColumns+` FROM photos WHERE photos.user_id = ? AND photos.publish_at IS NOT NULL
+		AND photos.deleted_at IS NULL ORDER BY photos.publish_at, photos.id`, UserID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting scheduled photos: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var photos []Photo
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	err = a.attachPhotoMentions(photos)
+	if err != nil {
+		return nil, err
+	}
+	err = a.attachSavedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.attachLikedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// ReschedulePhoto sposta a publishAt (formato YYYYMMDDHHmmSS) la pubblicazione della foto programmata photoID;
+// restituisce sql.ErrNoRows se la foto non esiste o è già stata pubblicata
+func (a *appdbimpl) ReschedulePhoto(photoID string, publishAt string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`UPDATE photos SET publish_at = ?, timestamp = ? WHERE id = ? AND publish_at IS NOT NULL`,
+		publishAt, publishAt, PhotoID)
+	if err != nil {
+		return fmt.Errorf("rescheduling photo: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// PublishDuePhotos pubblica le foto programmate entro now (formato YYYYMMDDHHmmSS) e restituisce quante ne ha
+// pubblicate. Il timestamp delle foto resta quello programmato.
+func (a *appdbimpl) PublishDuePhotos(now string) (int64, error) {
+	result, err := a.c.Exec(`UPDATE photos SET publish_at = NULL WHERE publish_at IS NOT NULL AND publish_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("publishing scheduled photos: %w", err)
+	}
+
+	published, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("getting affected rows: %w", err)
+	}
+
+	return published, nil
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// TrashPhoto sposta nel cestino la foto photoID, con deletedAt (formato YYYYMMDDHHmmSS) come momento
+// dell'eliminazione; restituisce sql.ErrNoRows se la foto non esiste o è già nel cestino. Likes e commenti restano
+// finché la foto non viene eliminata definitivamente con DeletePhoto.
+func (a *appdbimpl) TrashPhoto(photoID string, deletedAt string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`UPDATE photos SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, deletedAt, PhotoID)
+	if err != nil {
+		return fmt.Errorf("trashing photo: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RestorePhoto riporta la foto photoID dal cestino al profilo; restituisce sql.ErrNoRows se la foto non è nel cestino
+func (a *appdbimpl) RestorePhoto(photoID string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	result, err := a.c.Exec(`UPDATE photos SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, PhotoID)
+	if err != nil {
+		return fmt.Errorf("restoring photo: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("getting affected rows: %w", err)
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetTrashedPhotosByUserID restituisce le foto nel cestino dell'utente userID, dall'ultima eliminata
+func (a *appdbimpl) GetTrashedPhotosByUserID(userID string) ([]Photo, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	rows, err := a.c.Query(`SELECT `+photoColumns+` FROM photos WHERE photos.user_id = ? AND photos.deleted_at IS NOT NULL
+		ORDER BY photos.deleted_at DESC, photos.id DESC`, UserID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting trashed photos: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var photos []Photo
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	err = a.attachPhotoMentions(photos)
+	if err != nil {
+		return nil, err
+	}
+	err = a.attachSavedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.attachLikedByMe(photos, UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return photos, nil
+}
+
+// GetPhotosDeletedBefore restituisce gli ID delle foto di tutti gli utenti spostate nel cestino prima di deletedBefore
+// (formato YYYYMMDDHHmmSS)
+func (a *appdbimpl) GetPhotosDeletedBefore(deletedBefore string) ([]int, error) {
+	rows, err := a.c.Query(`SELECT id FROM photos WHERE deleted_at IS NOT NULL AND deleted_at < ?`, deletedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("selecting trashed photos: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var photoIDs []int
+	for rows.Next() {
+		var photoID int
+		err = rows.Scan(&photoID)
+		if err != nil {
+			return nil, fmt.Errorf("scanning photo ID: %w", err)
+		}
+		photoIDs = append(photoIDs, photoID)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return photoIDs, nil
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// uploadColumns sono le colonne di uploads lette da scanUpload, nello stesso ordine
+const uploadColumns = `id, user_id, length, upload_offset, metadata, expires_at, photo_id`
+
+// scanUpload legge un upload selezionato con uploadColumns
+func scanUpload(row rowScanner) (Upload, error) {
+	var upload Upload
+	err := row.Scan(&upload.ID, &upload.UserID, &upload.Length, &upload.Offset, &upload.Metadata, &upload.ExpiresAt, &upload.PhotoID)
+	return upload, err
+}
+
+// SetUpload registra un nuovo upload ripristinabile
+func (a *appdbimpl) SetUpload(upload Upload) error {
+	_, err := a.c.Exec(`INSERT INTO uploads (id, user_id, length, upload_offset, metadata, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		upload.ID, upload.UserID, upload.Length, upload.Offset, upload.Metadata, upload.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("inserting upload: %w", err)
+	}
+
+	return nil
+}
+
+// GetUploadByID restituisce l'upload con id=uploadID
+func (a *appdbimpl) GetUploadByID(uploadID string) (Upload, error) {
+	upload, err := scanUpload(a.c.QueryRow(`SELECT `+uploadColumns+` FROM uploads WHERE id = ?`, uploadID))
+	if err != nil {
+		return upload, fmt.Errorf("selecting upload: %w", err)
+	}
+
+	return upload, nil
+}
+
+// UpdateUploadOffset salva quanti byte dell'upload sono stati ricevuti e ne sposta la scadenza
+func (a *appdbimpl) UpdateUploadOffset(uploadID string, offset int64, expiresAt string) error {
+	_, err := a.c.Exec(`UPDATE uploads SET upload_offset = ?, expires_at = ? WHERE id = ?`, offset, expiresAt, uploadID)
+	if err != nil {
+		return fmt.Errorf("updating upload offset: %w", err)
+	}
+
+	return nil
+}
+
+// SetUploadPhoto segna l'upload come completato, collegandolo alla foto creata
+func (a *appdbimpl) SetUploadPhoto(uploadID string, photoID int64) error {
+	_, err := a.c.Exec(`UPDATE uploads SET photo_id = ? WHERE id = ?`, photoID, uploadID)
+	if err != nil {
+		return fmt.Errorf("updating upload photo: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUpload elimina l'upload; la foto eventualmente creata non viene toccata
+func (a *appdbimpl) DeleteUpload(uploadID string) error {
+	_, err := a.c.Exec(`DELETE FROM uploads WHERE id = ?`, uploadID)
+	if err != nil {
+		return fmt.Errorf("deleting upload: %w", err)
+	}
+
+	return nil
+}
+
+// GetExpiredUploads restituisce gli upload scaduti prima di now (formato YYYYMMDDHHmmSS)
+func (a *appdbimpl) GetExpiredUploads(now string) ([]Upload, error) {
+	rows, err := a.c.Query(`SELECT `+uploadColumns+` FROM uploads WHERE expires_at < ?`, now)
+	if err != nil {
+		return nil, fmt.Errorf("selecting expired uploads: %w", err)
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			log.Println("Error closing rows:", err)
+			return
+		}
+	}(rows) // Ensure rows are closed after function returns
+
+	var uploads []Upload
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning upload: %w", err)
+		}
+		uploads = append(uploads, upload)
+	}
+
+	// Check for errors encountered during iteration
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	return uploads, nil
+}
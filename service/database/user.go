@@ -33,7 +33,7 @@ func (a *appdbimpl) SetUser(name string) error {
 // GetUserByUsername restituisce i dettagli dell'user in users con username=name
 func (a *appdbimpl) GetUserByUsername(name string) (User, error) {
 	var user User
-	err := a.c.QueryRow(`SELECT * FROM users WHERE username = ?`, name).Scan(&user.ID, &user.Username)
+	err := a.c.QueryRow(`SELECT id, username FROM users WHERE username = ?`, name).Scan(&user.ID, &user.Username)
 	if err != nil {
 		return user, fmt.Errorf("selecting user: %w", err)
 	}
@@ -128,7 +128,8 @@ func (a *appdbimpl) FollowUser(userID string, followedUserID string) error {
 	return nil
 }
 
-// UnfollowUser cancella dalla tabella followers la relazione tra i 2 account
+// UnfollowUser cancella dalla tabella followers la relazione tra i 2 account, o la richiesta di follow se non è ancora
+// stata approvata
 func (a *appdbimpl) UnfollowUser(userID string, followedUserID string) error {
 
 	UserID, err := strconv.Atoi(userID)
@@ -146,96 +147,76 @@ func (a *appdbimpl) UnfollowUser(userID string, followedUserID string) error {
 		return fmt.Errorf("unfollowing user: %w", err)
 	}
 
-	return nil
-}
-
-// GetFollowers restituisce lista dei followers per followed_id=userID contando i follower da followers
-func (a *appdbimpl) GetFollowers(userID string) ([]User, error) {
-	var followers []User
-
-	UserID, err := strconv.Atoi(userID)
+	// Annulla anche l'eventuale richiesta di follow in attesa
+	_, err = a.c.Exec(`DELETE FROM follow_requests WHERE requester_id = ? AND target_id = ?`, UserID, FollowedUserID)
 	if err != nil {
-		return followers, fmt.Errorf("converting user ID to integer: %w", err)
+		return fmt.Errorf("deleting follow request: %w", err)
 	}
 
-	rows, err := a.c.Query(`SELECT follower_id FROM followers WHERE followed_id = ?`, UserID)
-	if err != nil {
-		return followers, fmt.Errorf("selecting followers: %w", err)
-	}
-
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			log.Println("Error closing rows:", err)
-			return
-		}
-	}(rows) // Ensure rows are closed after function returns
-
-	for rows.Next() {
-		var followerID int
-		err = rows.Scan(&followerID)
-		if err != nil {
-			return followers, fmt.Errorf("scanning follower ID: %w", err)
-		}
-
-		var follower User
-		err = a.c.QueryRow(`SELECT ID, username FROM users WHERE ID = ?`, followerID).Scan(&follower.ID, &follower.Username)
-		if err != nil {
-			return followers, fmt.Errorf("selecting follower: %w", err)
-		}
-
-		followers = append(followers, follower)
-	}
+	return nil
+}
 
-	// Check for errors encountered during iteration
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating rows: %w", err)
-	}
+// GetFollowers restituisce al più limit follower dell'utente userID, dal follow più recente, a partire dal primo
+// precedente al follow after (0 per iniziare dal più recente), con i rapporti con l'utente viewerID. Se prefix non è
+// vuoto restituisce solo i follower con username che inizia per prefix.
+func (a *appdbimpl) GetFollowers(userID string, viewerID string, prefix string, after int, limit int) ([]Follow, error) {
+	return a.getFollowList("followed_id", "follower_id", userID, viewerID, prefix, after, limit)
+}
 
-	return followers, nil
+// GetFollows restituisce al più limit utenti seguiti dall'utente userID, con gli stessi criteri di GetFollowers
+func (a *appdbimpl) GetFollows(userID string, viewerID string, prefix string, after int, limit int) ([]Follow, error) {
+	return a.getFollowList("follower_id", "followed_id", userID, viewerID, prefix, after, limit)
 }
 
-// GetFollows restituisce i dettagli dei follows in user_profile con username=name
-func (a *appdbimpl) GetFollows(userID string) ([]User, error) {
-	var follows []User
+// getFollowList restituisce una pagina degli utenti nella colonna listColumn dei follow in cui userID è nella colonna
+// userColumn. Gli utenti con cui viewerID ha un ban, in un verso o nell'altro, non sono restituiti.
+func (a *appdbimpl) getFollowList(userColumn string, listColumn string, userID string, viewerID string, prefix string, after int, limit int) ([]Follow, error) {
 
 	UserID, err := strconv.Atoi(userID)
 	if err != nil {
-		return follows, fmt.Errorf("converting user ID to integer: %w", err)
+		return nil, fmt.Errorf("converting user ID to integer: %w", err)
 	}
 
-	log.Printf("Getting follows for user ID: %s", userID)
-
-	rows, err := a.c.Query(`SELECT followed_id FROM followers WHERE follower_id = ?`, UserID)
+	ViewerID, err := strconv.Atoi(viewerID)
 	if err != nil {
-		return follows, fmt.Errorf("selecting follows: %w", err)
+		return nil, fmt.Errorf("converting viewer ID to integer: %w", err)
 	}
 
+	// I caratteri speciali di LIKE nel prefisso vanno cercati letteralmente
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+
+	rows, err := a.c.Query(`SELECT followers.id, users.id, users.username,
+		EXISTS (SELECT 1 FROM followers f WHERE f.follower_id = :viewer AND f.followed_id = users.id),
+		EXISTS (SELECT 1 FROM followers f WHERE f.follower_id = users.id AND f.followed_id = :viewer),
+		EXISTS (SELECT 1 FROM follow_requests fr WHERE fr.requester_id = :viewer AND fr.target_id = users.id)
+		FROM followers JOIN users ON users.id = followers.`+listColumn+`
+		WHERE followers.`+userColumn+` = :user AND (:after = 0 OR followers.id < :after)
+		AND users.username LIKE :pattern ESCAPE '\'
+		AND NOT EXISTS (SELECT 1 FROM bans
+			WHERE (bans.user_id = :viewer AND bans.banned_id = users.id)
+			OR (bans.user_id = users.id AND bans.banned_id = :viewer))
+		ORDER BY followers.id DESC LIMIT :limit`,
+		sql.Named("user", UserID), sql.Named("viewer", ViewerID), sql.Named("pattern", pattern),
+		sql.Named("after", after), sql.Named("limit", limit))
+	if err != nil {
+		return nil, fmt.Errorf("selecting follows: %w", err)
+	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
 		if err != nil {
 			log.Println("Error closing rows:", err)
+			return
 		}
 	}(rows) // Ensure rows are closed after function returns
 
+	var follows []Follow
 	for rows.Next() {
-		var followedID int
-		err = rows.Scan(&followedID)
+		var f Follow
+		err = rows.Scan(&f.ID, &f.UserID, &f.Username, &f.FollowedByMe, &f.FollowsMe, &f.FollowRequested)
 		if err != nil {
-			return follows, fmt.Errorf("scanning followed ID: %w", err)
+			return nil, fmt.Errorf("scanning follow: %w", err)
 		}
-
-		var followed User
-		// Query per ottenere il nome utente dell'utente seguito
-		err = a.c.QueryRow(`SELECT ID, username FROM users WHERE ID = ?`, followedID).Scan(&followed.ID, &followed.Username)
-		if err != nil {
-			return follows, fmt.Errorf("selecting followed: %w", err)
-		}
-
-		// Log dopo aver aggiunto ciascun utente seguito alla lista follows
-		log.Printf("Added followed user: ID = %d, Username = %s", followed.ID, followed.Username)
-
-		follows = append(follows, followed)
+		follows = append(follows, f)
 	}
 
 	// Check for errors encountered during iteration
@@ -312,7 +293,8 @@ func (a *appdbimpl) UnbanUser(userID string, bannedUserID string) error {
 	return nil
 }
 
-// IsBanned controlla se l'utente è stato bannato da un altro utente specifico e restituisce true o false
+// IsBanned controlla se l'utente userID è stato bannato dall'utente otherUserID e restituisce true o false; per il
+// rapporto completo in entrambi i versi c'è GetRelationship
 func (a *appdbimpl) IsBanned(userID string, otherUserID string) (bool, error) {
 	// Converti userID e otherUserID in interi
 	UserID, err := strconv.Atoi(userID)
@@ -336,6 +318,44 @@ func (a *appdbimpl) IsBanned(userID string, otherUserID string) (bool, error) {
 	return exists, nil
 }
 
+// GetRelationship restituisce il rapporto dell'utente userID con l'utente otherUserID, in entrambi i versi, con una
+// sola query; restituisce sql.ErrNoRows se otherUserID non esiste
+func (a *appdbimpl) GetRelationship(userID string, otherUserID string) (Relationship, error) {
+	var relationship Relationship
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return relationship, fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	OtherUserID, err := strconv.Atoi(otherUserID)
+	if err != nil {
+		return relationship, fmt.Errorf("converting other user ID to integer: %w", err)
+	}
+
+	relationship.UserID = UserID
+	relationship.OtherID = OtherUserID
+	err = a.c.QueryRow(`SELECT
+		EXISTS (SELECT 1 FROM followers WHERE follower_id = :user AND followed_id = :other),
+		EXISTS (SELECT 1 FROM followers WHERE follower_id = :other AND followed_id = :user),
+		EXISTS (SELECT 1 FROM bans WHERE user_id = :user AND banned_id = :other),
+		EXISTS (SELECT 1 FROM bans WHERE user_id = :other AND banned_id = :user),
+		EXISTS (SELECT 1 FROM follow_requests WHERE requester_id = :user AND target_id = :other),
+		EXISTS (SELECT 1 FROM follow_requests WHERE requester_id = :other AND target_id = :user),
+		EXISTS (SELECT 1 FROM mutes WHERE user_id = :user AND muted_id = :other)
+		FROM users WHERE id = :other`,
+		sql.Named("user", UserID), sql.Named("other", OtherUserID)).Scan(&relationship.Follows,
+		&relationship.FollowedBy, &relationship.Banned, &relationship.BannedBy, &relationship.FollowRequested,
+		&relationship.FollowRequestedBy, &relationship.Muted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return relationship, err
+	} else if err != nil {
+		return relationship, fmt.Errorf("selecting relationship: %w", err)
+	}
+
+	return relationship, nil
+}
+
 // GetBans restituisce la lista degli utenti bannati da un determinato utente
 func (a *appdbimpl) GetBans(userID string) ([]User, error) {
 	var bans []User
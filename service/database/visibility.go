@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// Livelli di visibilità di una foto
+const (
+	// VisibilityPublic: la foto è visibile a tutti gli utenti
+	VisibilityPublic = "public"
+	// VisibilityFollowers: la foto è visibile solo ai follower del proprietario
+	VisibilityFollowers = "followers"
+	// VisibilityPrivate: la foto è visibile solo al proprietario
+	VisibilityPrivate = "private"
+)
+
+// IsValidVisibility indica se visibility è uno dei livelli di visibilità
+func IsValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPublic, VisibilityFollowers, VisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// photoVisibleTo è la condizione SQL, su una riga di photos, vera se la foto è visibile all'utente indicato dal
+// parametro sql.Named("viewer", ...). Il proprietario vede sempre le proprie foto; le foto degli account privati sono
+// visibili solo ai follower approvati, anche se pubbliche, e le foto programmate o archiviate non sono visibili agli
+// altri utenti finché non vengono pubblicate o tolte dall'archivio. Le foto nel cestino non sono visibili a nessuno,
+// nemmeno al proprietario, che le trova nel cestino. Ogni query che restituisce foto di altri utenti deve applicarla.
+const photoVisibleTo = `(photos.deleted_at IS NULL AND (photos.user_id = :viewer
+	OR (photos.publish_at IS NULL AND photos.archived_at IS NULL AND (
+		(photos.visibility = 'public' AND NOT (SELECT vu.private FROM users vu WHERE vu.id = photos.user_id))
+		OR (photos.visibility IN ('public', 'followers') AND EXISTS (SELECT 1 FROM followers vf WHERE vf.followed_id = photos.user_id AND vf.follower_id = :viewer))))))`
+
+// photoPublished è la condizione SQL, su una riga di photos, vera se la foto è già stata pubblicata. Le foto
+// programmate non compaiono nel profilo, nemmeno al proprietario, che le trova tra le foto programmate.
+const photoPublished = `photos.publish_at IS NULL`
+
+// IsPhotoVisible indica se la foto photoID è visibile all'utente viewerID; restituisce sql.ErrNoRows se la foto non
+// esiste
+func (a *appdbimpl) IsPhotoVisible(photoID string, viewerID string) (bool, error) {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return false, fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	ViewerID, err := strconv.Atoi(viewerID)
+	if err != nil {
+		return false, fmt.Errorf("converting viewer ID to integer: %w", err)
+	}
+
+	var visible bool
+	err = a.c.QueryRow(`SELECT `+photoVisibleTo+` FROM photos WHERE photos.id = :photo`,
+		sql.Named("photo", PhotoID), sql.Named("viewer", ViewerID)).Scan(&visible)
+	if err != nil {
+		return false, fmt.Errorf("checking photo visibility: %w", err)
+	}
+
+	return visible, nil
+}
+
+// SetPhotoVisibility cambia la visibilità della foto photoID
+func (a *appdbimpl) SetPhotoVisibility(photoID string, visibility string) error {
+
+	PhotoID, err := strconv.Atoi(photoID)
+	if err != nil {
+		return fmt.Errorf("converting photo ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE photos SET visibility = ? WHERE id = ?`, visibility, PhotoID)
+	if err != nil {
+		return fmt.Errorf("updating photo visibility: %w", err)
+	}
+
+	return nil
+}
+
+// GetDefaultVisibility restituisce la visibilità predefinita delle nuove foto dell'utente userID
+func (a *appdbimpl) GetDefaultVisibility(userID string) (string, error) {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return "", fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	var visibility string
+	err = a.c.QueryRow(`SELECT default_visibility FROM users WHERE id = ?`, UserID).Scan(&visibility)
+	if err != nil {
+		return "", fmt.Errorf("selecting default visibility: %w", err)
+	}
+
+	return visibility, nil
+}
+
+// SetDefaultVisibility cambia la visibilità predefinita delle nuove foto dell'utente userID; le foto già pubblicate
+// mantengono la loro visibilità
+func (a *appdbimpl) SetDefaultVisibility(userID string, visibility string) error {
+
+	UserID, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("converting user ID to integer: %w", err)
+	}
+
+	_, err = a.c.Exec(`UPDATE users SET default_visibility = ? WHERE id = ?`, visibility, UserID)
+	if err != nil {
+		return fmt.Errorf("updating default visibility: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import "testing"
+
+func TestPhotoVisibleTo(t *testing.T) {
+	db := newTestDB(t)
+	owner := newTestUser(t, db, "owner")
+	follower := newTestUser(t, db, "follower")
+	stranger := newTestUser(t, db, "stranger")
+
+	err := db.FollowUser(follower, owner)
+	if err != nil {
+		t.Fatalf("following: %v", err)
+	}
+
+	public := newTestPhoto(t, db, owner, VisibilityPublic, false)
+	followers := newTestPhoto(t, db, owner, VisibilityFollowers, false)
+	private := newTestPhoto(t, db, owner, VisibilityPrivate, false)
+	scheduled := newTestPhoto(t, db, owner, VisibilityPublic, true)
+	archived := newTestPhoto(t, db, owner, VisibilityPublic, false)
+	trashed := newTestPhoto(t, db, owner, VisibilityPublic, false)
+
+	err = db.ArchivePhoto(archived, testTimestamp)
+	if err != nil {
+		t.Fatalf("archiving: %v", err)
+	}
+	err = db.TrashPhoto(trashed, testTimestamp)
+	if err != nil {
+		t.Fatalf("trashing: %v", err)
+	}
+
+	check := func(t *testing.T, photoID string, viewerID string, want bool) {
+		t.Helper()
+		visible, err := db.IsPhotoVisible(photoID, viewerID)
+		if err != nil {
+			t.Fatalf("IsPhotoVisible(%s, %s): %v", photoID, viewerID, err)
+		}
+		if visible != want {
+			t.Errorf("IsPhotoVisible(%s, %s) = %v, want %v", photoID, viewerID, visible, want)
+		}
+	}
+
+	tests := []struct {
+		name                     string
+		photoID                  string
+		owner, follower, visitor bool
+	}{
+		{"public", public, true, true, true},
+		{"followers", followers, true, true, false},
+		{"private", private, true, false, false},
+		{"scheduled", scheduled, true, false, false},
+		{"archived", archived, true, false, false},
+		{"trashed", trashed, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check(t, tt.photoID, owner, tt.owner)
+			check(t, tt.photoID, follower, tt.follower)
+			check(t, tt.photoID, stranger, tt.visitor)
+		})
+	}
+
+	// In un account privato anche le foto pubbliche sono visibili solo ai follower approvati
+	err = db.SetPrivate(owner, true)
+	if err != nil {
+		t.Fatalf("making account private: %v", err)
+	}
+	t.Run("public in private account", func(t *testing.T) {
+		check(t, public, owner, true)
+		check(t, public, follower, true)
+		check(t, public, stranger, false)
+	})
+
+	// Una richiesta di follow in attesa non basta
+	err = db.SetFollowRequest(stranger, owner, testTimestamp)
+	if err != nil {
+		t.Fatalf("requesting follow: %v", err)
+	}
+	t.Run("pending follow request", func(t *testing.T) {
+		check(t, public, stranger, false)
+		check(t, followers, stranger, false)
+	})
+}